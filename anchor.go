@@ -0,0 +1,239 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// Anchor chooses the terminal polygon of the polarity graph in the quantitative review
+type Anchor interface {
+	// Terminal returns the index of the terminal polygon and its polarity
+	Terminal(polygons []*geos.Geometry, envelope *geos.Geometry) (int, string, error)
+}
+
+const (
+	// FIRSTANCHOR anchors polarity at the first polygon, which is positive
+	FIRSTANCHOR = "first"
+	// EDGEANCHOR treats the largest polygon touching the scene edge as water
+	EDGEANCHOR = "edge"
+	// SEEDANCHOR anchors polarity at the polygon containing a known land or water seed
+	SEEDANCHOR = "seed"
+	// WATER is the name of the polarity of water polygons
+	WATER = "water"
+	// LAND is the name of the polarity of land polygons
+	LAND = "land"
+)
+
+// newAnchor returns the Anchor with the given name.
+// The seed is either "x,y" or the name of a GeoJSON file and is only used by the seed anchor.
+// It is transformed into the working CRS of the scenes.
+func newAnchor(name, seed, seedPolarity string, working CRS) (Anchor, error) {
+	switch name {
+	case FIRSTANCHOR:
+		return firstAnchor{}, nil
+	case EDGEANCHOR:
+		return edgeAnchor{}, nil
+	case SEEDANCHOR:
+		var (
+			result seedAnchor
+			err    error
+		)
+		switch seedPolarity {
+		case WATER:
+			result.polarity = NEGATIVE
+		case LAND:
+			result.polarity = POSITIVE
+		default:
+			return nil, fmt.Errorf("Unknown seed polarity %v; expected %v or %v", seedPolarity, WATER, LAND)
+		}
+		if result.seed, err = parseSeed(seed, working); err != nil {
+			return nil, err
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("Unknown anchor %v; expected %v, %v or %v", name, FIRSTANCHOR, EDGEANCHOR, SEEDANCHOR)
+	}
+}
+
+// findsSea reports whether the anchor tells water from land, so that the land of a quantitative review
+// shows which way is seaward. The first anchor does not: its polarity depends on the polygonizer's output order.
+func findsSea(anchor Anchor) bool {
+	_, first := anchor.(firstAnchor)
+	return !first
+}
+
+// parseSeed reads a seed geometry from "x,y" (longitude and latitude)
+// or a GeoJSON file and transforms it into the working CRS
+func parseSeed(input string, working CRS) (*geos.Geometry, error) {
+	var (
+		gjInput  interface{}
+		geometry *geos.Geometry
+		seeds    []*geos.Geometry
+		seedCRS  CRS = geographic{}
+		crsName  string
+		x, y     float64
+		err      error
+	)
+	if input == "" {
+		return nil, errors.New("A seed anchor requires a seed")
+	}
+	if parts := strings.Split(input, ","); len(parts) == 2 {
+		if x, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err == nil {
+			if y, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
+				gjInput = &geojson.Point{Type: geojson.POINT, Coordinates: []float64{x, y}}
+			}
+		}
+	}
+	if gjInput == nil {
+		if gjInput, crsName, err = readGeoJSON(input, ""); err != nil {
+			return nil, fmt.Errorf("Seed %v is neither a coordinate pair nor a GeoJSON file: %v", input, err)
+		}
+		if crsName != "" {
+			if seedCRS, err = parseCRS(crsName); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if gjInput, err = transformGeoJSON(gjInput, reprojectFunc(seedCRS, working)); err != nil {
+		return nil, err
+	}
+	for _, current := range geojson.ToGeometryArray(gjInput) {
+		if geometry, err = toGeos(current); err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, geometry)
+	}
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("Seed %v contains no geometries", input)
+	}
+	if len(seeds) == 1 {
+		return seeds[0], nil
+	}
+	if geometry, err = geos.NewCollection(geos.GEOMETRYCOLLECTION, seeds...); err != nil {
+		return nil, err
+	}
+	return geometry.UnaryUnion()
+}
+
+// firstAnchor is the original behavior: the first polygon is positive,
+// so polarity depends on the order the polygonizer returns polygons
+type firstAnchor struct{}
+
+// Terminal returns the first polygon
+func (a firstAnchor) Terminal(polygons []*geos.Geometry, envelope *geos.Geometry) (int, string, error) {
+	return 0, POSITIVE, nil
+}
+
+// edgeAnchor assumes the largest polygon touching the edge of the scene is water
+type edgeAnchor struct{}
+
+// Terminal returns the largest polygon touching the envelope shell
+func (a edgeAnchor) Terminal(polygons []*geos.Geometry, envelope *geos.Geometry) (int, string, error) {
+	var (
+		edge        *geos.Geometry
+		area        float64
+		largestArea float64
+		intersects  bool
+		err         error
+		result      = -1
+	)
+	if edge, err = envelope.Shell(); err != nil {
+		return 0, "", err
+	}
+	for inx, polygon := range polygons {
+		if intersects, err = polygon.Intersects(edge); err != nil {
+			return 0, "", err
+		}
+		if !intersects {
+			continue
+		}
+		if area, err = polygon.Area(); err != nil {
+			return 0, "", err
+		}
+		if result == -1 || area > largestArea {
+			result = inx
+			largestArea = area
+		}
+	}
+	if result == -1 {
+		return 0, "", errors.New("No polygon touches the edge of the scene")
+	}
+	return result, NEGATIVE, nil
+}
+
+// seedAnchor uses a known land or water geometry
+type seedAnchor struct {
+	seed     *geos.Geometry
+	polarity string
+}
+
+// Terminal returns the polygon that overlaps the seed the most, the first of them if several overlap it equally.
+// Point seeds select the polygon that contains them. A point on the boundary between two polygons
+// is contained by neither, so it is rejected rather than assigned to whichever comes first.
+func (a seedAnchor) Terminal(polygons []*geos.Geometry, envelope *geos.Geometry) (int, string, error) {
+	var (
+		intersection *geos.Geometry
+		gType        geos.GeometryType
+		area         float64
+		largestArea  float64
+		intersects   bool
+		contains     bool
+		err          error
+		result       = -1
+	)
+	if gType, err = a.seed.Type(); err != nil {
+		return 0, "", err
+	}
+	for inx, polygon := range polygons {
+		if gType == geos.POINT || gType == geos.MULTIPOINT {
+			if contains, err = polygon.Contains(a.seed); err != nil {
+				return 0, "", err
+			}
+			if contains {
+				return inx, a.polarity, nil
+			}
+			continue
+		}
+		if intersects, err = polygon.Intersects(a.seed); err != nil {
+			return 0, "", err
+		}
+		if !intersects {
+			continue
+		}
+		if intersection, err = polygon.Intersection(a.seed); err != nil {
+			return 0, "", err
+		}
+		if area, err = intersection.Area(); err != nil {
+			return 0, "", err
+		}
+		if result == -1 || area > largestArea {
+			result = inx
+			largestArea = area
+		}
+	}
+	if result == -1 {
+		return 0, "", errors.New("The seed is not inside any polygon of the scene")
+	}
+	return result, a.polarity, nil
+}
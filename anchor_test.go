@@ -0,0 +1,136 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// rectangle returns a rectangular polygon
+func rectangle(t *testing.T, minX, minY, maxX, maxY float64) *geos.Geometry {
+	result, err := toGeos(&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{
+		{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}, {minX, minY}}}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return result
+}
+
+// TestNewAnchor makes anchors by name
+func TestNewAnchor(t *testing.T) {
+	tests := []struct {
+		name, seed, seedPolarity string
+		expected                 Anchor
+		polarity                 string
+	}{
+		{FIRSTANCHOR, "", WATER, firstAnchor{}, ""},
+		{EDGEANCHOR, "", WATER, edgeAnchor{}, ""},
+		{SEEDANCHOR, "10,60", WATER, nil, NEGATIVE},
+		{SEEDANCHOR, "10,60", LAND, nil, POSITIVE},
+		{SEEDANCHOR, "", WATER, nil, ""},
+		{SEEDANCHOR, "10,60", "ice", nil, ""},
+		{"middle", "", WATER, nil, ""}}
+	for _, test := range tests {
+		anchor, err := newAnchor(test.name, test.seed, test.seedPolarity, geographic{})
+		switch {
+		case test.expected != nil:
+			if err != nil || !reflect.DeepEqual(anchor, test.expected) {
+				t.Errorf("Expected %v to be %v, received %v, %v", test.name, test.expected, anchor, err)
+			}
+		case test.polarity != "":
+			if seed, ok := anchor.(seedAnchor); err != nil || !ok || seed.polarity != test.polarity {
+				t.Errorf("Expected a %v seed anchor, received %v, %v", test.polarity, anchor, err)
+			}
+		case err == nil:
+			t.Errorf("Expected an error for %v anchor %v (%v), received %v", test.name, test.seed, test.seedPolarity, anchor)
+		}
+	}
+}
+
+// TestTerminal chooses the terminal polygon with each anchor
+func TestTerminal(t *testing.T) {
+	var (
+		envelope = rectangle(t, 0, 0, 100, 100)
+		// Land to the south, water to the north and a polygon away from the edge
+		polygons = []*geos.Geometry{
+			rectangle(t, 0, 0, 100, 30),
+			rectangle(t, 0, 30, 100, 100),
+			rectangle(t, 40, 50, 60, 70)}
+		point = func(x, y float64) *geos.Geometry {
+			result, err := geos.NewPoint(geos.NewCoord(x, y))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			return result
+		}
+	)
+	tests := []struct {
+		name     string
+		anchor   Anchor
+		polygons []*geos.Geometry
+		terminal int
+		polarity string
+		fails    bool
+	}{
+		{"first", firstAnchor{}, polygons, 0, POSITIVE, false},
+		{"edge", edgeAnchor{}, polygons, 1, NEGATIVE, false},
+		{"edge away from the edge", edgeAnchor{}, polygons[2:], 0, "", true},
+		{"water seed", seedAnchor{seed: point(50, 10), polarity: NEGATIVE}, polygons, 0, NEGATIVE, false},
+		{"land seed", seedAnchor{seed: point(10, 60), polarity: POSITIVE}, polygons, 1, POSITIVE, false},
+		{"area seed", seedAnchor{seed: rectangle(t, 0, 25, 100, 40), polarity: NEGATIVE}, polygons, 1, NEGATIVE, false},
+		{"seed outside", seedAnchor{seed: point(200, 200), polarity: NEGATIVE}, polygons, 0, "", true},
+		{"seed on a shoreline", seedAnchor{seed: point(50, 30), polarity: NEGATIVE}, polygons, 0, "", true}}
+	for _, test := range tests {
+		terminal, polarity, err := test.anchor.Terminal(test.polygons, envelope)
+		if test.fails {
+			if err == nil {
+				t.Errorf("Expected the %v anchor to fail, received %v, %v", test.name, terminal, polarity)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("The %v anchor failed: %v", test.name, err)
+			continue
+		}
+		if terminal != test.terminal || polarity != test.polarity {
+			t.Errorf("Expected the %v anchor to choose %v (%v), received %v (%v)", test.name, test.terminal, test.polarity, terminal, polarity)
+		}
+	}
+}
+
+// TestPolygonDepths links touching polygons and rejects polygons that touch nothing
+func TestPolygonDepths(t *testing.T) {
+	polygons := []*geos.Geometry{
+		rectangle(t, 0, 0, 100, 30),
+		rectangle(t, 0, 30, 100, 60),
+		rectangle(t, 0, 60, 100, 100)}
+	depths, err := polygonDepths(polygons, 2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if expected := []int{2, 1, 0}; !reflect.DeepEqual(depths, expected) {
+		t.Errorf("Expected depths %v, received %v", expected, depths)
+	}
+	polygons = append(polygons, rectangle(t, 200, 200, 210, 210))
+	if _, err = polygonDepths(polygons, 0); err == nil {
+		t.Error("Expected an error for a polygon that touches no other")
+	}
+}
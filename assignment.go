@@ -0,0 +1,212 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import "math"
+
+// assign finds the one-to-one assignment of rows to columns with the lowest total cost
+// using the Hungarian algorithm. Pairs that may not be assigned have an infinite cost.
+// As many rows as possible are assigned and, among those assignments, the cheapest is chosen.
+// The result holds the column assigned to each row, or -1 if the row is unassigned.
+// Ties are broken by row and column order so the result is deterministic.
+func assign(cost [][]float64) []int {
+	var (
+		rows      = len(cost)
+		columns   int
+		forbidden = 1.0
+	)
+	for _, row := range cost {
+		if len(row) > columns {
+			columns = len(row)
+		}
+		for _, value := range row {
+			if !math.IsInf(value, 1) {
+				forbidden += math.Abs(value)
+			}
+		}
+	}
+	result := make([]int, rows)
+	for inx := range result {
+		result[inx] = -1
+	}
+	if rows == 0 || columns == 0 {
+		return result
+	}
+
+	// Pad to a square matrix; padding costs nothing and forbidden pairs
+	// cost more than every allowed pair combined
+	size := rows
+	if columns > size {
+		size = columns
+	}
+	matrix := make([][]float64, size)
+	for inx := range matrix {
+		matrix[inx] = make([]float64, size)
+		for jnx := 0; jnx < size && inx < rows; jnx++ {
+			if jnx >= len(cost[inx]) {
+				continue
+			}
+			if math.IsInf(cost[inx][jnx], 1) {
+				matrix[inx][jnx] = forbidden
+			} else {
+				matrix[inx][jnx] = cost[inx][jnx]
+			}
+		}
+	}
+
+	// Row and column potentials; owner[j] is the 1-based row assigned to column j
+	// and column 0 is a sentinel
+	var (
+		u     = make([]float64, size+1)
+		v     = make([]float64, size+1)
+		owner = make([]int, size+1)
+		way   = make([]int, size+1)
+	)
+	for inx := 1; inx <= size; inx++ {
+		owner[0] = inx
+		column := 0
+		minimum := make([]float64, size+1)
+		used := make([]bool, size+1)
+		for jnx := range minimum {
+			minimum[jnx] = math.Inf(1)
+		}
+		for {
+			used[column] = true
+			row := owner[column]
+			delta := math.Inf(1)
+			next := 0
+			for jnx := 1; jnx <= size; jnx++ {
+				if used[jnx] {
+					continue
+				}
+				if current := matrix[row-1][jnx-1] - u[row] - v[jnx]; current < minimum[jnx] {
+					minimum[jnx] = current
+					way[jnx] = column
+				}
+				if minimum[jnx] < delta {
+					delta = minimum[jnx]
+					next = jnx
+				}
+			}
+			for jnx := 0; jnx <= size; jnx++ {
+				if used[jnx] {
+					u[owner[jnx]] += delta
+					v[jnx] -= delta
+				} else {
+					minimum[jnx] -= delta
+				}
+			}
+			column = next
+			if owner[column] == 0 {
+				break
+			}
+		}
+		// Follow the augmenting path back to the sentinel
+		for column != 0 {
+			previous := way[column]
+			owner[column] = owner[previous]
+			column = previous
+		}
+	}
+
+	for jnx := 1; jnx <= size; jnx++ {
+		inx, column := owner[jnx]-1, jnx-1
+		if inx < rows && column < len(cost[inx]) && !math.IsInf(cost[inx][column], 1) {
+			result[inx] = column
+		}
+	}
+	return result
+}
+
+// candidate is a column that a row may be assigned to and the cost of doing so
+type candidate struct {
+	index int
+	cost  float64
+}
+
+// assignCandidates is assign for sparse costs, where each row lists the columns it may be
+// assigned to in increasing order. Rows and columns that share no candidates are independent,
+// so each connected group of them is assigned separately.
+func assignCandidates(candidates [][]candidate, columns int) []int {
+	var (
+		rows   = len(candidates)
+		parent = make([]int, rows+columns)
+		result = make([]int, rows)
+	)
+	// Union-find over rows (0..rows-1) and columns (rows..rows+columns-1)
+	var find func(int) int
+	find = func(node int) int {
+		if parent[node] != node {
+			parent[node] = find(parent[node])
+		}
+		return parent[node]
+	}
+	for inx := range parent {
+		parent[inx] = inx
+	}
+	for inx, row := range candidates {
+		for _, current := range row {
+			if first, second := find(inx), find(rows+current.index); first != second {
+				parent[second] = first
+			}
+		}
+	}
+
+	// Gather the rows and columns of each group in increasing order
+	groupRows := make(map[int][]int)
+	groupColumns := make(map[int][]int)
+	var roots []int
+	for inx := 0; inx < rows; inx++ {
+		result[inx] = -1
+		root := find(inx)
+		if _, ok := groupRows[root]; !ok {
+			roots = append(roots, root)
+		}
+		groupRows[root] = append(groupRows[root], inx)
+	}
+	for jnx := 0; jnx < columns; jnx++ {
+		root := find(rows + jnx)
+		groupColumns[root] = append(groupColumns[root], jnx)
+	}
+
+	for _, root := range roots {
+		groupedRows, groupedColumns := groupRows[root], groupColumns[root]
+		if len(groupedColumns) == 0 {
+			continue
+		}
+		position := make(map[int]int, len(groupedColumns))
+		for jnx, column := range groupedColumns {
+			position[column] = jnx
+		}
+		cost := make([][]float64, len(groupedRows))
+		for inx, row := range groupedRows {
+			cost[inx] = make([]float64, len(groupedColumns))
+			for jnx := range cost[inx] {
+				cost[inx][jnx] = math.Inf(1)
+			}
+			for _, current := range candidates[row] {
+				cost[inx][position[current.index]] = current.cost
+			}
+		}
+		for inx, jnx := range assign(cost) {
+			if jnx >= 0 {
+				result[groupedRows[inx]] = groupedColumns[jnx]
+			}
+		}
+	}
+	return result
+}
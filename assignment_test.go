@@ -0,0 +1,74 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestAssign checks the assignments made for several cost matrices
+func TestAssign(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		cost     [][]float64
+		expected []int
+	}{
+		{[][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}, []int{1, 0, 2}},
+		// Taking the cheapest pair first would leave the most expensive one
+		{[][]float64{{1, 2}, {2, 100}}, []int{1, 0}},
+		{[][]float64{{5}, {1}, {3}}, []int{-1, 0, -1}},
+		{[][]float64{{3, 1, 2}}, []int{1}},
+		{[][]float64{{1, inf}, {2, inf}}, []int{0, -1}},
+		// Assigning every row wins over a cheaper partial assignment
+		{[][]float64{{1, 5}, {2, inf}}, []int{1, 0}},
+		{[][]float64{}, []int{}},
+	}
+	for _, c := range cases {
+		if result := assign(c.cost); !reflect.DeepEqual(c.expected, result) {
+			t.Errorf("Expected %v for %v, received %v", c.expected, c.cost, result)
+		}
+	}
+}
+
+// TestAssignCandidates checks that assigning independent groups separately
+// gives the same result as assigning the whole cost matrix
+func TestAssignCandidates(t *testing.T) {
+	inf := math.Inf(1)
+	cost := [][]float64{
+		{1, inf, 2, inf},
+		{inf, 4, inf, 1},
+		{2, inf, 100, inf},
+		{inf, inf, inf, inf},
+		{inf, 2, inf, inf}}
+	candidates := make([][]candidate, len(cost))
+	for inx, row := range cost {
+		for jnx, value := range row {
+			if !math.IsInf(value, 1) {
+				candidates[inx] = append(candidates[inx], candidate{index: jnx, cost: value})
+			}
+		}
+	}
+	expected := assign(cost)
+	if result := assignCandidates(candidates, 4); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, received %v", expected, result)
+	}
+	if expected := []int{2, 3, 0, -1, 1}; !reflect.DeepEqual(expected, assign(cost)) {
+		t.Errorf("Expected %v, received %v", expected, assign(cost))
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+
+	"github.com/montanaflynn/stats"
+	"github.com/paulsmith/gogeos/geos"
+)
+
+// BIASCORRECTION is the key for the FeatureCollection property containing
+// the scene bias the detected scene was corrected by
+const BIASCORRECTION = "bias_correction"
+
+// SceneBias is the systematic offset of the detected scene from the baseline,
+// such as a georegistration error in the imagery
+type SceneBias struct {
+	// Easting and Northing are the median offsets of the matched features (detected minus baseline)
+	Easting  float64 `json:"easting"`
+	Northing float64 `json:"northing"`
+	// EastingMAD and NorthingMAD are the median absolute deviations of the offsets
+	EastingMAD  float64 `json:"easting_mad"`
+	NorthingMAD float64 `json:"northing_mad"`
+	// Features is the number of matched features the bias was estimated from
+	Features int    `json:"features"`
+	Units    string `json:"units"`
+}
+
+// sceneBias estimates the bias of a scene as the median of the offsets of its matched features,
+// taken from their registrations, so that a few bad matches do not distort it.
+// It returns nil if there are no registrations.
+func sceneBias(registrations []*Registration) *SceneBias {
+	var eastings, northings stats.Float64Data
+	if len(registrations) == 0 {
+		return nil
+	}
+	for _, registration := range registrations {
+		// Registrations move the detected lines onto the baseline so the bias is the opposite
+		eastings = append(eastings, -registration.Easting)
+		northings = append(northings, -registration.Northing)
+	}
+	result := SceneBias{Features: len(registrations), Units: METERS}
+	result.Easting, result.EastingMAD = medianAndMAD(eastings)
+	result.Northing, result.NorthingMAD = medianAndMAD(northings)
+	return &result
+}
+
+// medianAndMAD returns the median of the values and their median absolute deviation from it
+func medianAndMAD(input stats.Float64Data) (float64, float64) {
+	var deviations stats.Float64Data
+	median, _ := input.Median()
+	for _, value := range input {
+		deviations = append(deviations, math.Abs(value-median))
+	}
+	mad, _ := deviations.Median()
+	return median, mad
+}
+
+// estimateBias matches two scenes and estimates the bias of the detected scene.
+// The bias has to be known before the review measures anything, so it cannot reuse the review's registrations
+// and matches the scenes itself, registering each group as matchFeature does.
+// It uses the registrations rather than the centroid offsets in detection_bias because a fragment,
+// or a line clipped by the edge of the scene, moves its centroid along the coast as far as any real offset;
+// ICP only pairs detected points that have a counterpart on the baseline.
+func estimateBias(detected, baseline *Scene, options qualitativeOptions) (*SceneBias, error) {
+	var (
+		matches       *sceneMatches
+		registration  *Registration
+		registrations []*Registration
+		baselineLine  *geos.Geometry
+		detectedLine  *geos.Geometry
+		err           error
+	)
+	if matches, err = matchScenes(detected, baseline, options); err != nil {
+		return nil, err
+	}
+	for inx, group := range matches.baselineGroups {
+		if group == nil || group.baseline[0] != inx {
+			continue
+		}
+		if _, baselineLine, detectedLine, _, err = mergeGroup(group, matches.features, matches.baselineLines, matches.detectedLines); err != nil {
+			return nil, err
+		}
+		if registration, err = registerLines(baselineLine, detectedLine, math.Inf(1), options); err != nil {
+			return nil, err
+		}
+		if registration != nil {
+			registrations = append(registrations, registration)
+		}
+	}
+	return sceneBias(registrations), nil
+}
+
+// correctBias moves the detected scene to remove its bias
+func correctBias(detected *Scene, bias *SceneBias) error {
+	return detected.transform(func(x, y float64) (float64, float64) {
+		return x - bias.Easting, y - bias.Northing
+	})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// TestSceneBias checks that one bad match does not distort the bias
+func TestSceneBias(t *testing.T) {
+	registrations := []*Registration{
+		{Easting: -3, Northing: 4},
+		{Easting: -3.5, Northing: 4},
+		{Easting: -2.5, Northing: 4},
+		{Easting: -40, Northing: 4}}
+	expected := &SceneBias{Easting: 3.25, Northing: -4, EastingMAD: 0.5, Features: 4, Units: METERS}
+	if result := sceneBias(registrations); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %+v, received %+v", expected, result)
+	}
+	if result := sceneBias(nil); result != nil {
+		t.Errorf("Expected no bias without registrations, received %+v", result)
+	}
+}
+
+// shiftedL is an L-shaped line with its corner at (x+50, y)
+func shiftedL(x, y float64) *geojson.LineString {
+	return &geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{x, y}, {x + 50, y}, {x + 50, y + 50}}}
+}
+
+// TestEstimateBias checks the bias of a scene offset from its baseline
+func TestEstimateBias(t *testing.T) {
+	var (
+		bias *SceneBias
+		err  error
+	)
+	baseline := featureScene(shiftedL(0, 0), shiftedL(200, 0))
+	detected := featureScene(shiftedL(3, -2), shiftedL(203, -2))
+	options := qualitativeOptions{tolerance: 5, matchTolerance: 5, closureRule: STRICTCLOSURE, sampleSpacing: 1}
+	if bias, err = estimateBias(&detected, &baseline, options); err != nil {
+		t.Fatal(err.Error())
+	}
+	if bias == nil {
+		t.Fatal("Expected a bias, received none")
+	}
+	if bias.Features != 2 {
+		t.Errorf("Expected a bias from 2 features, received %v", bias.Features)
+	}
+	if math.Abs(bias.Easting-3) > 0.01 || math.Abs(bias.Northing+2) > 0.01 {
+		t.Errorf("Expected a bias of (3, -2), received (%v, %v)", bias.Easting, bias.Northing)
+	}
+}
+
+// TestCorrectBias checks that the detected scene is moved back by its bias
+func TestCorrectBias(t *testing.T) {
+	var (
+		features []*geojson.Feature
+		err      error
+	)
+	detected := featureScene(shiftedL(3, -2))
+	if err = correctBias(&detected, &SceneBias{Easting: 3, Northing: -2, Units: METERS}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if features, err = detected.features(); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := shiftedL(0, 0).Coordinates
+	if result := features[0].Geometry.(*geojson.LineString).Coordinates; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, received %v", expected, result)
+	}
+}
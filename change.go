@@ -0,0 +1,198 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+const (
+	// REVIEWMODE is the analysis mode for the qualitative and quantitative reviews
+	REVIEWMODE = "review"
+	// CHANGEMODE is the analysis mode for erosion and accretion between the baseline and detected scenes
+	CHANGEMODE = "change"
+	// CHANGE is the key for the GeoJSON property indicating whether land was gained or lost
+	CHANGE = "change"
+	// ACCRETION is land in the detected scene that was not land in the baseline
+	ACCRETION = "accretion"
+	// EROSION is land in the baseline that is not land in the detected scene
+	EROSION = "erosion"
+	// AREA is the key for the GeoJSON property containing the area of a change polygon
+	AREA = "area"
+	// minChangeArea is the smallest change polygon in square meters. Smaller ones are slivers
+	// left by floating point error along edges the baseline and detected land share.
+	minChangeArea = 1e-6
+)
+
+// ChangeResult is the land gained and lost between the baseline and detected scenes
+type ChangeResult struct {
+	BaselineLandArea float64 `json:"baseline_land_area"`
+	DetectedLandArea float64 `json:"detected_land_area"`
+	AccretionArea    float64 `json:"accretion_area"`
+	ErosionArea      float64 `json:"erosion_area"`
+	NetChange        float64 `json:"net_change"`
+	AccretionCount   int     `json:"accretion_count"`
+	ErosionCount     int     `json:"erosion_count"`
+	Units            string  `json:"units"`
+}
+
+// landGeometry returns the land of a quantitative review as a single geometry.
+// Positive polygons are land without their holes;
+// holes in negative polygons are islands and so are land as well.
+func landGeometry(result *QuantitativeResult) (*geos.Geometry, error) {
+	var (
+		land     []*geos.Geometry
+		holes    []*geos.Geometry
+		geometry *geos.Geometry
+		err      error
+	)
+	for _, polygon := range result.Polygons {
+		switch polygon.Polarity {
+		case POSITIVE:
+			land = append(land, polygon.geometry)
+		case NEGATIVE:
+			if holes, err = polygon.geometry.Holes(); err != nil {
+				return nil, err
+			}
+			for _, hole := range holes {
+				if geometry, err = geos.PolygonFromGeom(hole); err != nil {
+					return nil, err
+				}
+				land = append(land, geometry)
+			}
+		}
+	}
+	if geometry, err = geos.NewCollection(geos.GEOMETRYCOLLECTION, land...); err != nil {
+		return nil, err
+	}
+	return geometry.UnaryUnion()
+}
+
+// changeFeatures returns a GeoJSON Feature for each polygon in the input
+// along with their total area
+func changeFeatures(input *geos.Geometry, change string) ([]*geojson.Feature, float64, error) {
+	var (
+		features []*geojson.Feature
+		polygons []*geos.Geometry
+		area     float64
+		total    float64
+		err      error
+	)
+	if polygons, err = extractPolygons(input); err != nil {
+		return nil, 0, err
+	}
+	for _, polygon := range polygons {
+		var (
+			gjGeometry interface{}
+			properties = make(map[string]interface{})
+		)
+		if area, err = polygon.Area(); err != nil {
+			return nil, 0, err
+		}
+		// Slivers from shared edges are not change
+		if area < minChangeArea {
+			continue
+		}
+		if gjGeometry, err = fromGeos(polygon); err != nil {
+			return nil, 0, err
+		}
+		properties[CHANGE] = change
+		properties[AREA] = area
+		properties[UNITS] = SQUAREMETERS
+		features = append(features, geojson.NewFeature(gjGeometry, "", properties))
+		total += area
+	}
+	return features, total, nil
+}
+
+// extractPolygons returns the polygons in the input, which may be a
+// Polygon, a MultiPolygon or a GeometryCollection
+func extractPolygons(input *geos.Geometry) ([]*geos.Geometry, error) {
+	var (
+		result  []*geos.Geometry
+		members []*geos.Geometry
+		gType   geos.GeometryType
+		err     error
+	)
+	if gType, err = input.Type(); err != nil {
+		return nil, err
+	}
+	switch gType {
+	case geos.POLYGON:
+		result = append(result, input)
+	case geos.MULTIPOLYGON, geos.GEOMETRYCOLLECTION:
+		if members, err = components(input); err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			var polygons []*geos.Geometry
+			if polygons, err = extractPolygons(member); err != nil {
+				return nil, err
+			}
+			result = append(result, polygons...)
+		}
+	}
+	return result, nil
+}
+
+// changeReview compares the land of the baseline and detected scenes,
+// returning accretion and erosion polygons and the scene totals
+func changeReview(baseline, detected *QuantitativeResult) (*geojson.FeatureCollection, *ChangeResult, error) {
+	var (
+		baselineLand *geos.Geometry
+		detectedLand *geos.Geometry
+		geometry     *geos.Geometry
+		accretion    []*geojson.Feature
+		erosion      []*geojson.Feature
+		err          error
+		result       = ChangeResult{Units: SQUAREMETERS}
+	)
+	if baselineLand, err = landGeometry(baseline); err != nil {
+		return nil, nil, err
+	}
+	if detectedLand, err = landGeometry(detected); err != nil {
+		return nil, nil, err
+	}
+	if result.BaselineLandArea, err = baselineLand.Area(); err != nil {
+		return nil, nil, err
+	}
+	if result.DetectedLandArea, err = detectedLand.Area(); err != nil {
+		return nil, nil, err
+	}
+
+	// Accretion is land(detected) minus land(baseline)
+	if geometry, err = detectedLand.Difference(baselineLand); err != nil {
+		return nil, nil, err
+	}
+	if accretion, result.AccretionArea, err = changeFeatures(geometry, ACCRETION); err != nil {
+		return nil, nil, err
+	}
+
+	// Erosion is land(baseline) minus land(detected)
+	if geometry, err = baselineLand.Difference(detectedLand); err != nil {
+		return nil, nil, err
+	}
+	if erosion, result.ErosionArea, err = changeFeatures(geometry, EROSION); err != nil {
+		return nil, nil, err
+	}
+
+	result.AccretionCount = len(accretion)
+	result.ErosionCount = len(erosion)
+	result.NetChange = result.AccretionArea - result.ErosionArea
+	return geojson.NewFeatureCollection(append(accretion, erosion...)), &result, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// TestChangeReview moves a shoreline north by 10 and drowns an island
+func TestChangeReview(t *testing.T) {
+	var (
+		water  *geos.Geometry
+		land   *geos.Geometry
+		fc     *geojson.FeatureCollection
+		result *ChangeResult
+		area   float64
+		err    error
+	)
+	if water, err = toGeos(&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{
+		{{0, 30}, {100, 30}, {100, 100}, {0, 100}, {0, 30}}, square(40, 50, 20)}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	baseline := &QuantitativeResult{Polygons: []PolygonResult{
+		{Polarity: POSITIVE, geometry: rectangle(t, 0, 0, 100, 30)},
+		{Polarity: NEGATIVE, geometry: water}}}
+	detected := &QuantitativeResult{Polygons: []PolygonResult{
+		{Polarity: POSITIVE, geometry: rectangle(t, 0, 0, 100, 40)},
+		{Polarity: NEGATIVE, geometry: rectangle(t, 0, 40, 100, 100)}}}
+
+	// The island in the water is land too
+	if land, err = landGeometry(baseline); err != nil {
+		t.Fatal(err.Error())
+	}
+	if area, _ = land.Area(); area != 3400 {
+		t.Errorf("Expected 3400 of baseline land, received %v", area)
+	}
+
+	if fc, result, err = changeReview(baseline, detected); err != nil {
+		t.Fatalf("Change review failed: %v", err)
+	}
+	expected := ChangeResult{
+		BaselineLandArea: 3400,
+		DetectedLandArea: 4000,
+		AccretionArea:    1000,
+		ErosionArea:      400,
+		NetChange:        600,
+		AccretionCount:   1,
+		ErosionCount:     1,
+		Units:            SQUAREMETERS}
+	if *result != expected {
+		t.Errorf("Expected %v, received %v", expected, *result)
+	}
+	if len(fc.Features) != 2 {
+		t.Fatalf("Expected 2 change polygons, received %v", len(fc.Features))
+	}
+	for inx, change := range []string{ACCRETION, EROSION} {
+		properties := fc.Features[inx].Properties
+		if properties[CHANGE] != change || properties[UNITS] != SQUAREMETERS {
+			t.Errorf("Expected feature %v to be %v in %v, received %v", inx, change, SQUAREMETERS, properties)
+		}
+	}
+	if area = fc.Features[0].Properties[AREA].(float64); area != 1000 {
+		t.Errorf("Expected 1000 of accretion, received %v", area)
+	}
+}
+
+// TestExtractPolygons finds the polygons in a collection and skips everything else
+func TestExtractPolygons(t *testing.T) {
+	var (
+		collection *geos.Geometry
+		polygons   []*geos.Geometry
+		err        error
+	)
+	if collection, err = toGeos(&geojson.GeometryCollection{Type: geojson.GEOMETRYCOLLECTION, Geometries: []interface{}{
+		&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {1, 1}}},
+		&geojson.MultiPolygon{Type: geojson.MULTIPOLYGON, Coordinates: [][][][]float64{{square(0, 0, 1)}, {square(5, 5, 1)}}},
+		&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{square(10, 10, 1)}}}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if polygons, err = extractPolygons(collection); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(polygons) != 3 {
+		t.Errorf("Expected 3 polygons, received %v", len(polygons))
+	}
+}
+
+// TestChangeFeatures skips slivers along shared edges
+func TestChangeFeatures(t *testing.T) {
+	var (
+		collection *geos.Geometry
+		features   []*geojson.Feature
+		total      float64
+		err        error
+	)
+	if collection, err = toGeos(&geojson.MultiPolygon{Type: geojson.MULTIPOLYGON, Coordinates: [][][][]float64{
+		{square(0, 0, 10)},
+		{{{20, 0}, {120, 0}, {120, 1e-12}, {20, 0}}}}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if features, total, err = changeFeatures(collection, EROSION); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(features) != 1 || total != 100 {
+		t.Errorf("Expected one change polygon of 100, received %v with a total of %v", len(features), total)
+	}
+}
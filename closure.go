@@ -0,0 +1,139 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/paulsmith/gogeos/geos"
+)
+
+const (
+	// STRICTCLOSURE only matches open lines with open lines and closed lines with closed lines
+	STRICTCLOSURE = "strict"
+	// IGNORECLOSURE matches lines regardless of whether they are closed
+	IGNORECLOSURE = "ignore"
+	// CLIPPEDCLOSURE is STRICTCLOSURE, except that lines clipped by the edge of the scene
+	// may be clipped rings, so they match closed lines as well as open ones
+	CLIPPEDCLOSURE = "clipped"
+)
+
+// closure describes whether a line is closed and whether clipping by the scene envelope
+// may have hidden whether it is closed
+type closure struct {
+	closed  bool
+	clipped bool
+}
+
+// checkClosureRule returns an error if the closure rule is unknown
+func checkClosureRule(rule string) error {
+	switch rule {
+	case STRICTCLOSURE, IGNORECLOSURE, CLIPPEDCLOSURE:
+		return nil
+	default:
+		return fmt.Errorf("Unknown closure rule %v; expected %v, %v or %v", rule, STRICTCLOSURE, IGNORECLOSURE, CLIPPEDCLOSURE)
+	}
+}
+
+// lineClosures describes the closure of each line.
+// A line is clipped if it leaves the envelope or both of its ends are within edgeTolerance of the edge of the envelope.
+func lineClosures(lines []*geos.Geometry, envelope *geos.Geometry, rule string, edgeTolerance float64) ([]closure, error) {
+	var (
+		result   = make([]closure, len(lines))
+		edge     *geos.Geometry
+		interior *geos.Geometry
+		err      error
+	)
+	if rule == CLIPPEDCLOSURE {
+		if edge, err = envelope.Boundary(); err != nil {
+			return nil, err
+		}
+		if interior, err = envelope.Buffer(edgeTolerance); err != nil {
+			return nil, err
+		}
+	}
+	for inx, line := range lines {
+		if result[inx].closed, err = line.IsClosed(); err != nil {
+			return nil, err
+		}
+		if rule != CLIPPEDCLOSURE {
+			continue
+		}
+		if result[inx].clipped, err = clipped(line, edge, interior, edgeTolerance); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// clipped returns true if a line leaves the interior or both of its ends are on the edge
+func clipped(line, edge, interior *geos.Geometry, edgeTolerance float64) (bool, error) {
+	var (
+		contained   bool
+		partClipped bool
+		gType       geos.GeometryType
+		lines       []*geos.Geometry
+		ends        [2]*geos.Geometry
+		distance    float64
+		err         error
+	)
+	if contained, err = interior.Contains(line); err != nil || !contained {
+		return !contained, err
+	}
+	// A baseline feature may have several lines; it is clipped if any of them is
+	if gType, err = line.Type(); err != nil {
+		return false, err
+	}
+	if gType == geos.MULTILINESTRING {
+		if lines, err = components(line); err != nil {
+			return false, err
+		}
+		for _, member := range lines {
+			if partClipped, err = clipped(member, edge, interior, edgeTolerance); err != nil || partClipped {
+				return partClipped, err
+			}
+		}
+		return false, nil
+	}
+	if ends[0], err = line.StartPoint(); err != nil {
+		return false, err
+	}
+	if ends[1], err = line.EndPoint(); err != nil {
+		return false, err
+	}
+	for _, end := range ends {
+		if distance, err = end.Distance(edge); err != nil {
+			return false, err
+		}
+		if distance > edgeTolerance {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// closuresMatch returns true if the rule allows lines with these closures to match
+func closuresMatch(baseline, detected closure, rule string) bool {
+	switch rule {
+	case IGNORECLOSURE:
+		return true
+	case CLIPPEDCLOSURE:
+		return baseline.closed == detected.closed || baseline.clipped || detected.clipped
+	default:
+		return baseline.closed == detected.closed
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// TestClosuresMatch checks each closure rule
+func TestClosuresMatch(t *testing.T) {
+	var (
+		open    = closure{}
+		ring    = closure{closed: true}
+		clipped = closure{clipped: true}
+	)
+	cases := []struct {
+		baseline, detected closure
+		rule               string
+		expected           bool
+	}{
+		{ring, ring, STRICTCLOSURE, true},
+		{ring, open, STRICTCLOSURE, false},
+		{ring, clipped, STRICTCLOSURE, false},
+		{ring, open, IGNORECLOSURE, true},
+		{ring, open, CLIPPEDCLOSURE, false},
+		{ring, clipped, CLIPPEDCLOSURE, true},
+		{open, clipped, CLIPPEDCLOSURE, true},
+		{open, open, CLIPPEDCLOSURE, true},
+	}
+	for _, c := range cases {
+		if result := closuresMatch(c.baseline, c.detected, c.rule); result != c.expected {
+			t.Errorf("Expected %v for %+v and %+v under %v, received %v", c.expected, c.baseline, c.detected, c.rule, result)
+		}
+	}
+}
+
+// TestLineClosures finds which lines are closed and which were clipped by the edge of the scene
+func TestLineClosures(t *testing.T) {
+	var (
+		lines    []*geos.Geometry
+		line     *geos.Geometry
+		closures []closure
+		err      error
+	)
+	cases := []struct {
+		name     string
+		geometry interface{}
+		expected closure
+	}{
+		{"an interior line",
+			&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{20, 20}, {50, 50}}},
+			closure{}},
+		{"an interior ring",
+			&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{20, 20}, {40, 20}, {40, 40}, {20, 20}}},
+			closure{closed: true}},
+		{"a ring crossing the edge",
+			&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{80, 40}, {120, 40}, {120, 60}, {80, 60}, {80, 40}}},
+			closure{closed: true, clipped: true}},
+		{"a ring cut by the edge",
+			&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{100, 40}, {80, 40}, {80, 60}, {100, 60}}},
+			closure{clipped: true}},
+		{"a line ending within tolerance of the edge",
+			&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0.5, 20}, {50, 50}, {99.5, 20}}},
+			closure{clipped: true}},
+		{"a line with one end at the edge",
+			&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0.5, 20}, {50, 50}}},
+			closure{}},
+		{"lines one of which is clipped",
+			&geojson.MultiLineString{Type: geojson.MULTILINESTRING, Coordinates: [][][]float64{{{20, 20}, {50, 50}}, {{0, 80}, {100, 80}}}},
+			closure{clipped: true}},
+	}
+	for _, c := range cases {
+		if line, err = toGeos(c.geometry); err != nil {
+			t.Fatal(err.Error())
+		}
+		lines = append(lines, line)
+	}
+	envelope := rectangle(t, 0, 0, 100, 100)
+	if closures, err = lineClosures(lines, envelope, CLIPPEDCLOSURE, 1); err != nil {
+		t.Fatal(err.Error())
+	}
+	for inx, c := range cases {
+		if closures[inx] != c.expected {
+			t.Errorf("Expected %+v for %v, received %+v", c.expected, c.name, closures[inx])
+		}
+	}
+
+	// Only the clipped rule looks for clipping
+	if closures, err = lineClosures(lines, envelope, STRICTCLOSURE, 1); err != nil {
+		t.Fatal(err.Error())
+	}
+	for inx, c := range cases {
+		if closures[inx].clipped {
+			t.Errorf("Expected %v not to be clipped under %v", c.name, STRICTCLOSURE)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// CRS is a coordinate reference system that can be converted
+// to and from WGS84 longitude and latitude
+type CRS interface {
+	String() string
+	ToGeographic(x, y float64) (float64, float64)
+	FromGeographic(lon, lat float64) (float64, float64)
+}
+
+// CRS84 is the default GeoJSON CRS: WGS84 longitude and latitude
+const CRS84 = "urn:ogc:def:crs:OGC:1.3:CRS84"
+
+var epsgPattern = regexp.MustCompile(`EPSG(?:/\d+/|:(?:[\d.]*:)?)(\d+)$`)
+
+// parseCRS returns the CRS for a name such as
+// urn:ogc:def:crs:OGC:1.3:CRS84, urn:ogc:def:crs:EPSG::32750 or EPSG:3857.
+// Only WGS84 geographic, UTM and Web Mercator are supported.
+func parseCRS(name string) (CRS, error) {
+	var (
+		code int
+		err  error
+	)
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if strings.HasSuffix(upper, "CRS84") || strings.HasSuffix(upper, "CRS:84") {
+		return geographic{}, nil
+	}
+	matches := epsgPattern.FindStringSubmatch(upper)
+	if matches == nil {
+		return nil, fmt.Errorf("Unrecognized CRS %v", name)
+	}
+	if code, err = strconv.Atoi(matches[1]); err != nil {
+		return nil, err
+	}
+	switch {
+	// GeoJSON coordinates are longitude, latitude regardless of the EPSG axis order
+	case code == 4326:
+		return geographic{}, nil
+	case code == 3857 || code == 900913:
+		return webMercator{}, nil
+	case code > 32600 && code <= 32660:
+		return utm{zone: code - 32600}, nil
+	case code > 32700 && code <= 32760:
+		return utm{zone: code - 32700, south: true}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported CRS %v", name)
+	}
+}
+
+// readGeoJSON parses a GeoJSON file and returns it along with the name of its CRS:
+// crsName if it is given, otherwise the name in its crs member, or "" if there is none.
+// The crs member is not read when crsName is given, so it can override a crs that cannot be read.
+func readGeoJSON(filename, crsName string) (interface{}, string, error) {
+	var (
+		bytes  []byte
+		result interface{}
+		err    error
+	)
+	if bytes, err = ioutil.ReadFile(filename); err != nil {
+		return nil, "", err
+	}
+	if result, err = geojson.Parse(bytes); err != nil {
+		return nil, "", err
+	}
+	if crsName != "" {
+		return result, crsName, nil
+	}
+	if crsName, err = crsMember(bytes); err != nil {
+		return nil, "", fmt.Errorf("Could not read the crs of %v: %v", filename, err)
+	}
+	return result, crsName, nil
+}
+
+// crsMember returns the name in the crs member of a GeoJSON document, or "" if there is none
+func crsMember(bytes []byte) (string, error) {
+	var input struct {
+		CRS *struct {
+			Type       string `json:"type"`
+			Properties struct {
+				Name string `json:"name"`
+			} `json:"properties"`
+		} `json:"crs"`
+	}
+	if err := json.Unmarshal(bytes, &input); err != nil {
+		return "", err
+	}
+	if input.CRS == nil {
+		return "", nil
+	}
+	if input.CRS.Type != "name" {
+		return "", fmt.Errorf("Unsupported crs type %v; only named CRSs are supported", input.CRS.Type)
+	}
+	return input.CRS.Properties.Name, nil
+}
+
+// reprojectFunc returns a transformFunc from one CRS to another
+func reprojectFunc(from, to CRS) transformFunc {
+	return func(x, y float64) (float64, float64) {
+		return to.FromGeographic(from.ToGeographic(x, y))
+	}
+}
+
+// sameCRS reports whether two CRSs are the same
+func sameCRS(first, second CRS) bool {
+	return first.String() == second.String()
+}
+
+// geographic is WGS84 longitude and latitude
+type geographic struct{}
+
+func (g geographic) String() string { return CRS84 }
+
+// ToGeographic returns the coordinate unchanged
+func (g geographic) ToGeographic(x, y float64) (float64, float64) { return x, y }
+
+// FromGeographic returns the coordinate unchanged
+func (g geographic) FromGeographic(lon, lat float64) (float64, float64) { return lon, lat }
+
+// webMercator is the spherical Mercator projection used by web maps
+type webMercator struct{}
+
+func (w webMercator) String() string { return "EPSG:3857" }
+
+// ToGeographic unprojects Web Mercator meters to longitude and latitude
+func (w webMercator) ToGeographic(x, y float64) (float64, float64) {
+	lon := x / wgs84A * 180 / math.Pi
+	lat := (2*math.Atan(math.Exp(y/wgs84A)) - math.Pi/2) * 180 / math.Pi
+	return lon, lat
+}
+
+// FromGeographic projects longitude and latitude to Web Mercator meters
+func (w webMercator) FromGeographic(lon, lat float64) (float64, float64) {
+	x := wgs84A * lon * math.Pi / 180
+	y := wgs84A * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+	return x, y
+}
@@ -19,9 +19,8 @@ package main
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"os/exec"
+	"math"
+	"sort"
 
 	"github.com/montanaflynn/stats"
 	"github.com/paulsmith/gogeos/geos"
@@ -38,6 +37,16 @@ func parseCoordArray(input [][]float64) []geos.Coord {
 	}
 	return result
 }
+func parsePolygon(input [][][]float64) (*geos.Geometry, error) {
+	var coordsArray [][]geos.Coord
+	if len(input) == 0 {
+		return nil, errors.New("Cannot create a polygon without a shell")
+	}
+	for inx := 0; inx < len(input); inx++ {
+		coordsArray = append(coordsArray, parseCoordArray(input[inx]))
+	}
+	return geos.NewPolygon(coordsArray[0], coordsArray[1:]...)
+}
 
 // toGeos takes a GeoJSON object and returns a GEOS geometry
 func toGeos(input interface{}) (*geos.Geometry, error) {
@@ -52,13 +61,7 @@ func toGeos(input interface{}) (*geos.Geometry, error) {
 	case *geojson.LineString:
 		geometry, err = geos.NewLineString(parseCoordArray(gt.Coordinates)...)
 	case *geojson.Polygon:
-		var coords []geos.Coord
-		var coordsArray [][]geos.Coord
-		for jnx := 0; jnx < len(gt.Coordinates); jnx++ {
-			coords = parseCoordArray(gt.Coordinates[jnx])
-			coordsArray = append(coordsArray, coords)
-		}
-		geometry, err = geos.NewPolygon(coordsArray[0], coordsArray[1:]...)
+		geometry, err = parsePolygon(gt.Coordinates)
 	case *geojson.MultiPoint:
 		var points []*geos.Geometry
 		var point *geos.Geometry
@@ -75,11 +78,27 @@ func toGeos(input interface{}) (*geos.Geometry, error) {
 			lineStrings = append(lineStrings, lineString)
 		}
 		geometry, err = geos.NewCollection(geos.MULTILINESTRING, lineStrings...)
-
-	case *geojson.GeometryCollection:
-		err = errors.New("Unimplemented GeometryCollection in toGeos")
 	case *geojson.MultiPolygon:
-		err = errors.New("Unimplemented MultiPolygon in toGeos")
+		var polygons []*geos.Geometry
+		var polygon *geos.Geometry
+		for jnx := 0; jnx < len(gt.Coordinates); jnx++ {
+			if polygon, err = parsePolygon(gt.Coordinates[jnx]); err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, polygon)
+		}
+		geometry, err = geos.NewCollection(geos.MULTIPOLYGON, polygons...)
+	case *geojson.GeometryCollection:
+		var geometries []*geos.Geometry
+		var member *geos.Geometry
+		for jnx := 0; jnx < len(gt.Geometries); jnx++ {
+			// Collections may nest so reenter
+			if member, err = toGeos(gt.Geometries[jnx]); err != nil {
+				return nil, err
+			}
+			geometries = append(geometries, member)
+		}
+		geometry, err = geos.NewCollection(geos.GEOMETRYCOLLECTION, geometries...)
 	case *geojson.Feature:
 		return toGeos(gt.Geometry)
 	default:
@@ -88,31 +107,150 @@ func toGeos(input interface{}) (*geos.Geometry, error) {
 	return geometry, err
 }
 
+func formatCoord(input geos.Coord) []float64 {
+	arr := [...]float64{input.X, input.Y}
+	return arr[:]
+}
+func formatCoordArray(input []geos.Coord) [][]float64 {
+	var result [][]float64
+	for inx := 0; inx < len(input); inx++ {
+		result = append(result, formatCoord(input[inx]))
+	}
+	return result
+}
+func formatPolygon(input *geos.Geometry) ([][][]float64, error) {
+	var (
+		result [][][]float64
+		ring   *geos.Geometry
+		holes  []*geos.Geometry
+		coords []geos.Coord
+		err    error
+	)
+	if ring, err = input.Shell(); err != nil {
+		return nil, err
+	}
+	if holes, err = input.Holes(); err != nil {
+		return nil, err
+	}
+	for _, ring = range append([]*geos.Geometry{ring}, holes...) {
+		if coords, err = ring.Coords(); err != nil {
+			return nil, err
+		}
+		result = append(result, formatCoordArray(coords))
+	}
+	return result, nil
+}
+
+// components returns the component geometries of a GEOS collection
+func components(input *geos.Geometry) ([]*geos.Geometry, error) {
+	var (
+		result   []*geos.Geometry
+		geometry *geos.Geometry
+		count    int
+		err      error
+	)
+	if count, err = input.NGeometry(); err != nil {
+		return nil, err
+	}
+	for inx := 0; inx < count; inx++ {
+		if geometry, err = input.Geometry(inx); err != nil {
+			return nil, err
+		}
+		result = append(result, geometry)
+	}
+	return result, nil
+}
+
 // fromGeos takes a GEOS geometry and returns a GeoJSON object
 func fromGeos(input *geos.Geometry) (interface{}, error) {
 	var (
-		result interface{}
-		err    error
-		gType  geos.GeometryType
-		coords []geos.Coord
+		result   interface{}
+		err      error
+		gType    geos.GeometryType
+		coords   []geos.Coord
+		members  []*geos.Geometry
+		geometry *geos.Geometry
 	)
-	gType, err = input.Type()
-	if err == nil {
-		switch gType {
-		case geos.LINESTRING:
-			coords, err = input.Coords()
-			if err == nil {
-				var coordinates [][]float64
-				for inx := 0; inx < len(coords); inx++ {
-					arr := [...]float64{coords[inx].X, coords[inx].Y}
-					coordinates = append(coordinates, arr[:])
-				}
-				result = geojson.LineString{Type: geojson.LINESTRING, Coordinates: coordinates}
+	if gType, err = input.Type(); err != nil {
+		return nil, err
+	}
+	switch gType {
+	case geos.POINT:
+		if coords, err = input.Coords(); err != nil {
+			return nil, err
+		}
+		if len(coords) == 0 {
+			return nil, errors.New("Cannot create a GeoJSON Point from an empty point")
+		}
+		result = &geojson.Point{Type: geojson.POINT, Coordinates: formatCoord(coords[0])}
+	case geos.LINESTRING, geos.LINEARRING:
+		// GeoJSON has no LinearRing so it becomes a closed LineString
+		if coords, err = input.Coords(); err != nil {
+			return nil, err
+		}
+		result = &geojson.LineString{Type: geojson.LINESTRING, Coordinates: formatCoordArray(coords)}
+	case geos.POLYGON:
+		var coordinates [][][]float64
+		if coordinates, err = formatPolygon(input); err != nil {
+			return nil, err
+		}
+		result = &geojson.Polygon{Type: geojson.POLYGON, Coordinates: coordinates}
+	case geos.MULTIPOINT:
+		var points [][]float64
+		if members, err = components(input); err != nil {
+			return nil, err
+		}
+		for _, geometry = range members {
+			if coords, err = geometry.Coords(); err != nil {
+				return nil, err
 			}
-		default:
-			err = fmt.Errorf("Unimplemented %T", gType)
+			if len(coords) == 0 {
+				return nil, errors.New("Cannot create a GeoJSON MultiPoint from an empty point")
+			}
+			points = append(points, formatCoord(coords[0]))
 		}
-
+		result = &geojson.MultiPoint{Type: geojson.MULTIPOINT, Coordinates: points}
+	case geos.MULTILINESTRING:
+		var lineStrings [][][]float64
+		if members, err = components(input); err != nil {
+			return nil, err
+		}
+		for _, geometry = range members {
+			if coords, err = geometry.Coords(); err != nil {
+				return nil, err
+			}
+			lineStrings = append(lineStrings, formatCoordArray(coords))
+		}
+		result = &geojson.MultiLineString{Type: geojson.MULTILINESTRING, Coordinates: lineStrings}
+	case geos.MULTIPOLYGON:
+		var polygons [][][][]float64
+		if members, err = components(input); err != nil {
+			return nil, err
+		}
+		for _, geometry = range members {
+			var coordinates [][][]float64
+			if coordinates, err = formatPolygon(geometry); err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, coordinates)
+		}
+		result = &geojson.MultiPolygon{Type: geojson.MULTIPOLYGON, Coordinates: polygons}
+	case geos.GEOMETRYCOLLECTION:
+		var gjGeometries []interface{}
+		if members, err = components(input); err != nil {
+			return nil, err
+		}
+		for _, geometry = range members {
+			var gjGeometry interface{}
+			// Reenter
+			if gjGeometry, err = fromGeos(geometry); err != nil {
+				return nil, err
+			}
+			gjGeometries = append(gjGeometries, gjGeometry)
+		}
+		result = &geojson.GeometryCollection{Type: geojson.GEOMETRYCOLLECTION, Geometries: gjGeometries}
+	default:
+		err = fmt.Errorf("Unexpected type in fromGeos: %v", gType)
 	}
 	return result, err
 }
@@ -155,47 +293,63 @@ func lineStringFromGeometry(input *geos.Geometry) (*geos.Geometry, error) {
 	return result, err
 }
 
-// multiPolygonize turns a slice of LineStrings into a MultiPolygon
-func multiPolygonize(input []*geos.Geometry) (*geos.Geometry, error) {
+// lineFromGeometry reduces a geometry of any type to its shoreline linework:
+// a LineString, or a MultiLineString if its lines do not join.
+// Points have no linework, so a geometry with nothing else cannot be reduced.
+func lineFromGeometry(input *geos.Geometry) (*geos.Geometry, error) {
 	var (
-		result         *geos.Geometry
-		mls            *geos.Geometry
-		err            error
-		geometryString string
-		file           *os.File
+		geometries []*geos.Geometry
+		lines      []*geos.Geometry
+		member     *geos.Geometry
+		result     *geos.Geometry
+		count      int
+		gType      geos.GeometryType
+		err        error
 	)
-
-	// Take the input, turn it into a MultiLineString so we can pass it to C++-land
-	mls, err = geos.NewCollection(geos.MULTILINESTRING, input[:]...)
-	if err != nil {
+	if geometries, err = shorelines(input); err != nil {
 		return nil, err
 	}
-
-	// Write the MLS to a temp file as WKT
-	geometryString, err = mls.ToWKT()
-	file, err = ioutil.TempFile("", "mls")
-	if err != nil {
-		return nil, err
+	for _, geometry := range geometries {
+		if gType, err = geometry.Type(); err != nil {
+			return nil, err
+		}
+		switch gType {
+		case geos.POINT, geos.MULTIPOINT:
+			continue
+		case geos.MULTILINESTRING:
+			if count, err = geometry.NGeometry(); err != nil {
+				return nil, err
+			}
+			for inx := 0; inx < count; inx++ {
+				if member, err = geometry.Geometry(inx); err != nil {
+					return nil, err
+				}
+				lines = append(lines, member)
+			}
+		default:
+			if geometry, err = lineStringFromGeometry(geometry); err != nil {
+				return nil, err
+			}
+			lines = append(lines, geometry)
+		}
 	}
-	defer os.Remove(file.Name())
-
-	file.Write([]byte(geometryString))
-
-	// Call our other application, which returns WKT
-	bfla := os.Getenv("BF_LINE_ANALYZER_DIR") + "/bld/bf_la"
-	cmd := exec.Command(bfla, "-mlp", file.Name())
-	bytes, err := cmd.Output()
-	if err != nil {
+	switch len(lines) {
+	case 0:
+		return nil, errors.New("Cannot create a line string from a geometry without lines")
+	case 1:
+		return lines[0], nil
+	}
+	if result, err = geos.NewCollection(geos.MULTILINESTRING, lines...); err != nil {
 		return nil, err
 	}
-	result, err = geos.FromWKT(string(bytes))
-
-	return result, err
+	// Join the lines when possible
+	return result.LineMerge()
 }
 
 // mlsToMPoly takes a MultiLineString and turns it into a MultiPolygon
+// within the given envelope.
 // This includes handling all of the interior (inner) rings
-func mlsToMPoly(input *geos.Geometry) (*geos.Geometry, error) {
+func mlsToMPoly(input *geos.Geometry, envelope *geos.Geometry, polygonizer Polygonizer) (*geos.Geometry, error) {
 	var (
 		result     *geos.Geometry
 		err        error
@@ -205,17 +359,12 @@ func mlsToMPoly(input *geos.Geometry) (*geos.Geometry, error) {
 		count      int
 		lineString *geos.Geometry
 		ring       *geos.Geometry
-		envelope   *geos.Geometry
 		polygon    *geos.Geometry
 		closed     bool
 	)
 
 	// Create two bins, one of rings and one of chords
 	// The envelope itself is the first chord
-	envelope, err = input.Envelope()
-	if err != nil {
-		return nil, err
-	}
 	ring, err = envelope.Shell()
 	if err != nil {
 		return nil, err
@@ -249,7 +398,7 @@ func mlsToMPoly(input *geos.Geometry) (*geos.Geometry, error) {
 
 	// Create a MultiPolygon covering the AOI
 	if len(chords) > 1 {
-		result, err = multiPolygonize(chords)
+		result, err = polygonizer.Polygonize(chords)
 	} else {
 		result, err = geos.NewCollection(geos.MULTIPOLYGON, envelope)
 	}
@@ -298,7 +447,10 @@ func mlsToMPoly(input *geos.Geometry) (*geos.Geometry, error) {
 	return result, err
 }
 
-func lineStringsToFloat64Data(first, second *geos.Geometry) (stats.Float64Data, error) {
+// lineStringsToFloat64Data measures the distance from points on the first line to the second.
+// The points are the vertices of the first line or, if spacing is positive,
+// points at equal arc-length intervals so that vertex density does not skew the results.
+func lineStringsToFloat64Data(first, second *geos.Geometry, spacing float64) (stats.Float64Data, error) {
 	var (
 		err      error
 		coords   []geos.Coord
@@ -307,10 +459,9 @@ func lineStringsToFloat64Data(first, second *geos.Geometry) (stats.Float64Data,
 		point    *geos.Geometry
 	)
 
-	if first, err = lineStringFromGeometry(first); err != nil {
+	if coords, err = lineCoords(first, spacing); err != nil {
 		return nil, err
 	}
-	coords, _ = first.Coords()
 	data = make([]float64, len(coords))
 	for inx := range coords {
 		if point, err = geos.NewPoint(coords[inx]); err != nil {
@@ -324,6 +475,97 @@ func lineStringsToFloat64Data(first, second *geos.Geometry) (stats.Float64Data,
 	return stats.LoadRawData(data), err
 }
 
+// lineCoords returns the vertices of a line, resampled if spacing is positive.
+// The lines of a MultiLineString are sampled separately and their points concatenated.
+func lineCoords(input *geos.Geometry, spacing float64) ([]geos.Coord, error) {
+	var (
+		result       []geos.Coord
+		coords       []geos.Coord
+		lines        []*geos.Geometry
+		geometryType geos.GeometryType
+		err          error
+	)
+	if geometryType, err = input.Type(); err != nil {
+		return nil, err
+	}
+	if geometryType == geos.MULTILINESTRING {
+		if lines, err = components(input); err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			if coords, err = lineCoords(line, spacing); err != nil {
+				return nil, err
+			}
+			result = append(result, coords...)
+		}
+		return result, nil
+	}
+	if input, err = lineStringFromGeometry(input); err != nil {
+		return nil, err
+	}
+	if result, err = input.Coords(); err != nil {
+		return nil, err
+	}
+	if spacing > 0 {
+		result = resampleCoords(result, spacing)
+	}
+	return result, nil
+}
+
+// lineCoordArrays returns the vertices of each line in a line or MultiLineString
+func lineCoordArrays(input *geos.Geometry) ([][]geos.Coord, error) {
+	var (
+		result [][]geos.Coord
+		lines  []*geos.Geometry
+		coords []geos.Coord
+		gType  geos.GeometryType
+		err    error
+	)
+	if gType, err = input.Type(); err != nil {
+		return nil, err
+	}
+	lines = []*geos.Geometry{input}
+	if gType == geos.MULTILINESTRING {
+		if lines, err = components(input); err != nil {
+			return nil, err
+		}
+	}
+	for _, line := range lines {
+		if coords, err = lineCoords(line, 0); err != nil {
+			return nil, err
+		}
+		result = append(result, coords)
+	}
+	return result, nil
+}
+
+// resampleCoords returns points at equal arc-length intervals along a line,
+// starting with its first vertex and ending with its last
+func resampleCoords(input []geos.Coord, spacing float64) []geos.Coord {
+	var result []geos.Coord
+	if len(input) == 0 {
+		return result
+	}
+	result = append(result, input[0])
+	// next is how far along the current segment the next sample falls
+	next := spacing
+	for inx := 1; inx < len(input); inx++ {
+		start, end := input[inx-1], input[inx]
+		length := math.Hypot(end.X-start.X, end.Y-start.Y)
+		for ; next <= length; next += spacing {
+			fraction := next / length
+			result = append(result, geos.NewCoord(start.X+fraction*(end.X-start.X), start.Y+fraction*(end.Y-start.Y)))
+		}
+		next -= length
+	}
+	// Always finish at the end of the line
+	last := input[len(input)-1]
+	if end := result[len(result)-1]; end.X != last.X || end.Y != last.Y {
+		result = append(result, last)
+	}
+	return result
+}
+
 func centroidCoordsXY(input *geos.Geometry) (float64, float64, error) {
 	var (
 		centroid         *geos.Geometry
@@ -344,9 +586,25 @@ func centroidCoordsXY(input *geos.Geometry) (float64, float64, error) {
 
 func displace(input *geos.Geometry, xShift float64, yShift float64) (*geos.Geometry, error) {
 	var (
-		coords []geos.Coord
-		err    error
+		coords       []geos.Coord
+		lines        []*geos.Geometry
+		geometryType geos.GeometryType
+		err          error
 	)
+	if geometryType, err = input.Type(); err != nil {
+		return nil, err
+	}
+	if geometryType == geos.MULTILINESTRING {
+		if lines, err = components(input); err != nil {
+			return nil, err
+		}
+		for inx := range lines {
+			if lines[inx], err = displace(lines[inx], xShift, yShift); err != nil {
+				return nil, err
+			}
+		}
+		return geos.NewCollection(geos.MULTILINESTRING, lines...)
+	}
 	if coords, err = input.Coords(); err != nil {
 		return nil, err
 	}
@@ -356,3 +614,81 @@ func displace(input *geos.Geometry, xShift float64, yShift float64) (*geos.Geome
 	}
 	return geos.NewLineString(coords[:]...)
 }
+
+// mergeFragments orders the fragments of a line along a reference line and orients them
+// in the direction of the reference, returning them as a MultiLineString.
+// It also returns the gaps between consecutive fragments measured along the reference.
+func mergeFragments(reference *geos.Geometry, fragments []*geos.Geometry) (*geos.Geometry, []float64, error) {
+	var (
+		coords     []geos.Coord
+		start, end *geos.Geometry
+		merged     *geos.Geometry
+		gaps       []float64
+		err        error
+	)
+	type interval struct {
+		from, to float64
+		line     *geos.Geometry
+	}
+	// A fragment may have several lines of its own
+	var lines []*geos.Geometry
+	for _, fragment := range fragments {
+		var gType geos.GeometryType
+		if gType, err = fragment.Type(); err != nil {
+			return nil, nil, err
+		}
+		if gType != geos.MULTILINESTRING {
+			lines = append(lines, fragment)
+			continue
+		}
+		var members []*geos.Geometry
+		if members, err = components(fragment); err != nil {
+			return nil, nil, err
+		}
+		lines = append(lines, members...)
+	}
+	intervals := make([]interval, len(lines))
+	for inx, fragment := range lines {
+		if fragment, err = lineStringFromGeometry(fragment); err != nil {
+			return nil, nil, err
+		}
+		if coords, err = fragment.Coords(); err != nil {
+			return nil, nil, err
+		}
+		if len(coords) == 0 {
+			return nil, nil, errors.New("Cannot merge an empty fragment")
+		}
+		if start, err = geos.NewPoint(coords[0]); err != nil {
+			return nil, nil, err
+		}
+		if end, err = geos.NewPoint(coords[len(coords)-1]); err != nil {
+			return nil, nil, err
+		}
+		intervals[inx] = interval{from: reference.Project(start), to: reference.Project(end), line: fragment}
+		// Reverse fragments that run against the reference
+		if intervals[inx].to < intervals[inx].from {
+			for left, right := 0, len(coords)-1; left < right; left, right = left+1, right-1 {
+				coords[left], coords[right] = coords[right], coords[left]
+			}
+			if intervals[inx].line, err = geos.NewLineString(coords...); err != nil {
+				return nil, nil, err
+			}
+			intervals[inx].from, intervals[inx].to = intervals[inx].to, intervals[inx].from
+		}
+	}
+	sort.SliceStable(intervals, func(inx, jnx int) bool { return intervals[inx].from < intervals[jnx].from })
+
+	lines = make([]*geos.Geometry, len(intervals))
+	reached := math.Inf(-1)
+	for inx, current := range intervals {
+		lines[inx] = current.line
+		if inx > 0 {
+			gaps = append(gaps, math.Max(0, current.from-reached))
+		}
+		reached = math.Max(reached, current.to)
+	}
+	if merged, err = geos.NewCollection(geos.MULTILINESTRING, lines...); err != nil {
+		return nil, nil, err
+	}
+	return merged, gaps, nil
+}
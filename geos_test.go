@@ -0,0 +1,206 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// square returns a closed ring of the given size anchored at (x, y)
+func square(x, y, size float64) [][]float64 {
+	return [][]float64{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y}}
+}
+
+// TestToGeosMultiPolygon makes sure every member polygon keeps its holes
+func TestToGeosMultiPolygon(t *testing.T) {
+	var (
+		geometry *geos.Geometry
+		polygon  *geos.Geometry
+		holes    []*geos.Geometry
+		gType    geos.GeometryType
+		count    int
+		area     float64
+		err      error
+	)
+	mp := &geojson.MultiPolygon{
+		Type: geojson.MULTIPOLYGON,
+		Coordinates: [][][][]float64{
+			{square(0, 0, 10), square(1, 1, 2)},
+			{square(20, 0, 10), square(21, 1, 2), square(25, 5, 2)}}}
+	if geometry, err = toGeos(mp); err != nil {
+		t.Fatalf("Failed to convert MultiPolygon: %v", err)
+	}
+	if gType, _ = geometry.Type(); gType != geos.MULTIPOLYGON {
+		t.Errorf("Expected MultiPolygon, received %v", gType)
+	}
+	if count, _ = geometry.NGeometry(); count != 2 {
+		t.Fatalf("Expected 2 polygons, received %v", count)
+	}
+	for inx, expected := range []int{1, 2} {
+		polygon, _ = geometry.Geometry(inx)
+		if holes, err = polygon.Holes(); err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(holes) != expected {
+			t.Errorf("Expected %v holes in polygon %v, received %v", expected, inx, len(holes))
+		}
+	}
+	if area, _ = geometry.Area(); area != 188 {
+		t.Errorf("Expected an area of 188, received %v", area)
+	}
+}
+
+// TestToGeosGeometryCollection converts a nested GeometryCollection
+func TestToGeosGeometryCollection(t *testing.T) {
+	var (
+		geometry *geos.Geometry
+		member   *geos.Geometry
+		gType    geos.GeometryType
+		count    int
+		err      error
+	)
+	inner := &geojson.GeometryCollection{
+		Type: geojson.GEOMETRYCOLLECTION,
+		Geometries: []interface{}{
+			&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{square(0, 0, 1)}}}}
+	gc := &geojson.GeometryCollection{
+		Type: geojson.GEOMETRYCOLLECTION,
+		Geometries: []interface{}{
+			&geojson.Point{Type: geojson.POINT, Coordinates: []float64{5, 5}},
+			&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {1, 1}}},
+			&geojson.MultiPolygon{Type: geojson.MULTIPOLYGON, Coordinates: [][][][]float64{{square(0, 0, 1)}}},
+			inner}}
+	if geometry, err = toGeos(gc); err != nil {
+		t.Fatalf("Failed to convert GeometryCollection: %v", err)
+	}
+	if count, _ = geometry.NGeometry(); count != 4 {
+		t.Fatalf("Expected 4 geometries, received %v", count)
+	}
+	for inx, expected := range []geos.GeometryType{geos.POINT, geos.LINESTRING, geos.MULTIPOLYGON, geos.GEOMETRYCOLLECTION} {
+		member, _ = geometry.Geometry(inx)
+		if gType, _ = member.Type(); gType != expected {
+			t.Errorf("Expected %v for geometry %v, received %v", expected, inx, gType)
+		}
+	}
+}
+
+// TestRoundTrip converts GeoJSON to GEOS and back again for every geometry type
+func TestRoundTrip(t *testing.T) {
+	var (
+		geometry *geos.Geometry
+		result   interface{}
+		err      error
+	)
+	inputs := []interface{}{
+		&geojson.Point{Type: geojson.POINT, Coordinates: []float64{1, 2}},
+		&geojson.MultiPoint{Type: geojson.MULTIPOINT, Coordinates: [][]float64{{1, 2}, {3, 4}}},
+		&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {1, 1}, {2, 0}}},
+		&geojson.MultiLineString{Type: geojson.MULTILINESTRING, Coordinates: [][][]float64{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}}},
+		&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{square(0, 0, 10), square(1, 1, 2), square(5, 5, 2)}},
+		&geojson.MultiPolygon{Type: geojson.MULTIPOLYGON, Coordinates: [][][][]float64{{square(0, 0, 10), square(1, 1, 2)}, {square(20, 0, 10)}}},
+		&geojson.GeometryCollection{Type: geojson.GEOMETRYCOLLECTION, Geometries: []interface{}{
+			&geojson.Point{Type: geojson.POINT, Coordinates: []float64{1, 2}},
+			&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{square(0, 0, 10), square(1, 1, 2)}},
+			&geojson.GeometryCollection{Type: geojson.GEOMETRYCOLLECTION, Geometries: []interface{}{
+				&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {1, 1}}}}}}}}
+	for _, input := range inputs {
+		if geometry, err = toGeos(input); err != nil {
+			t.Errorf("Failed to convert %T to GEOS: %v", input, err)
+			continue
+		}
+		if result, err = fromGeos(geometry); err != nil {
+			t.Errorf("Failed to convert %v from GEOS: %v", geometry.String(), err)
+			continue
+		}
+		if !reflect.DeepEqual(input, result) {
+			t.Errorf("Round trip of %T failed.\nExpected: %#v\nReceived: %#v", input, input, result)
+		}
+	}
+}
+
+// TestFromGeosLinearRing makes sure a LinearRing becomes a closed LineString
+func TestFromGeosLinearRing(t *testing.T) {
+	var (
+		ring   *geos.Geometry
+		result interface{}
+		err    error
+	)
+	coords := [...]geos.Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 0}}
+	if ring, err = geos.NewLinearRing(coords[:]...); err != nil {
+		t.Fatal(err.Error())
+	}
+	if result, err = fromGeos(ring); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := &geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %#v, received %#v", expected, result)
+	}
+}
+
+// TestResampleCoords samples a bent line at equal arc-length intervals
+func TestResampleCoords(t *testing.T) {
+	input := []geos.Coord{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}}
+	expected := []geos.Coord{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 8, Y: 0}, {X: 10, Y: 2}, {X: 10, Y: 5}}
+	if result := resampleCoords(input, 4); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, received %v", expected, result)
+	}
+}
+
+// TestMergeFragments orders and orients fragments along a reference line
+func TestMergeFragments(t *testing.T) {
+	var (
+		reference *geos.Geometry
+		fragments [2]*geos.Geometry
+		merged    *geos.Geometry
+		gaps      []float64
+		coords    []geos.Coord
+		err       error
+	)
+	if reference, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if fragments[0], err = geos.NewLineString(geos.NewCoord(60, 1), geos.NewCoord(90, 1)); err != nil {
+		t.Fatal(err.Error())
+	}
+	// The second fragment runs against the reference
+	if fragments[1], err = geos.NewLineString(geos.NewCoord(40, 1), geos.NewCoord(10, 1)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if merged, gaps, err = mergeFragments(reference, fragments[:]); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !reflect.DeepEqual([]float64{20}, gaps) {
+		t.Errorf("Expected gaps of [20], received %v", gaps)
+	}
+	if coords, err = lineCoords(merged, 0); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := [][2]float64{{10, 1}, {40, 1}, {60, 1}, {90, 1}}
+	if len(coords) != len(expected) {
+		t.Fatalf("Expected %v coordinates, received %v", len(expected), len(coords))
+	}
+	for inx, coord := range coords {
+		if coord.X != expected[inx][0] || coord.Y != expected[inx][1] {
+			t.Errorf("Expected %v at %v, received %v", expected[inx], inx, coord)
+		}
+	}
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/paulsmith/gogeos/geos"
 	"github.com/venicegeo/geojson-go/geojson"
@@ -26,7 +31,7 @@ import (
 
 func main() {
 	var (
-		args               = os.Args[1:]
+		args               []string
 		filenameD          string
 		filenameB          string
 		filenameOut        string
@@ -34,8 +39,102 @@ func main() {
 		err                error
 		detected, baseline Scene
 		fc                 *geojson.FeatureCollection
+		polygonizer        Polygonizer
+		anchor             Anchor
+		baselineResult     *QuantitativeResult
+		detectedResult     *QuantitativeResult
+		changeResult       *ChangeResult
+		qualitativeResult  *QualitativeResult
+		options            qualitativeOptions
+		projection         utm
+		x, y               float64
+		registration       *Registration
+		bias               *SceneBias
+		output             interface{}
 	)
 
+	mode := flag.String("mode", REVIEWMODE, "Analysis to perform: review, change or transect")
+	// The polygonizer defaults to BF_POLYGONIZER so it can be configured without changing the command line
+	polygonizerName := os.Getenv("BF_POLYGONIZER")
+	if polygonizerName == "" {
+		polygonizerName = GEOSPOLYGONIZER
+	}
+	flag.StringVar(&polygonizerName, "polygonizer", polygonizerName, "Polygonizer for the quantitative review: geos or bf_la")
+	polygonizerTimeout := flag.Duration("polygonizer-timeout", time.Minute, "Maximum time to wait for an external polygonizer")
+	anchorName := flag.String("anchor", FIRSTANCHOR, "How to anchor land/water polarity: first, edge or seed")
+	seed := flag.String("seed", "", "Seed for the seed anchor: x,y or a GeoJSON file")
+	seedPolarity := flag.String("seed-polarity", WATER, "Whether the seed is water or land")
+	flag.Float64Var(&options.tolerance, "tolerance", 30, "Distance in meters within which baseline and detected shorelines agree")
+	flag.Float64Var(&options.matchTolerance, "match-tolerance", 0, "Distance in meters within which baseline and detected features can match; 0 if they must intersect")
+	flag.StringVar(&options.closureRule, "closure", STRICTCLOSURE, "Whether open and closed lines can match: strict, ignore or clipped")
+	flag.Float64Var(&options.edgeTolerance, "edge-tolerance", 1, "Distance in meters from the edge of the scene within which a line counts as clipped")
+	flag.Float64Var(&options.sampleSpacing, "sample-spacing", 0, "Measure distances from points this many meters apart along each line instead of from its vertices")
+	percentiles := flag.String("percentiles", "90,95", "Comma-separated percentiles of the distances to report")
+	flag.Float64Var(&options.histogramWidth, "histogram-width", 5, "Width in meters of distance histogram bins; 0 for no histogram")
+	detectedCRS := flag.String("detected-crs", "", "CRS of the detected file, overriding its crs member")
+	baselineCRS := flag.String("baseline-crs", "", "CRS of the baseline file, overriding its crs member")
+	flag.Float64Var(&options.maxOffset, "max-offset", 100, "Farthest in meters along the baseline normal to look for the detected shoreline")
+	register := flag.Bool("register", false, "Register the detected scene onto the baseline (translation and rotation) before matching")
+	correct := flag.Bool("correct-bias", false, "Estimate the bias of the detected scene from its matched features and remove it before measuring")
+	var transects transectOptions
+	flag.Float64Var(&transects.spacing, "transect-spacing", 50, "Distance in meters between transects along the baseline")
+	flag.Float64Var(&transects.length, "transect-length", 500, "Distance in meters transects extend to either side of the baseline")
+	flag.Float64Var(&transects.confidence, "confidence", 95, "Confidence level in percent of the linear regression rate's confidence interval")
+	flag.Float64Var(&transects.uncertainty, "uncertainty", 0, "Uncertainty in meters of each shoreline position for the end point rate's confidence interval; 0 if unknown")
+	dates := flag.String("dates", "", "Comma-separated dates (YYYY-MM-DD) of the baseline and detected scenes for transect rates, overriding -date-property and file names")
+	dateProperty := flag.String("date-property", "date", "Feature property holding the date of each scene for transect rates")
+	filenameCSV := flag.String("csv", "", "Optional file for the transect statistics as CSV")
+	filenamePolygons := flag.String("polygons", "", "Optional file for the land/water polygons of the quantitative review")
+	flag.Parse()
+	args = flag.Args()
+
+	if options.percentiles, err = parsePercentiles(*percentiles); err != nil {
+		log.Printf("Invalid percentiles: %v\n", err)
+		os.Exit(1)
+	}
+
+	if options.histogramWidth < 0 {
+		log.Printf("Invalid histogram width %v; expected 0 or a positive width\n", options.histogramWidth)
+		os.Exit(1)
+	}
+
+	if err = checkMatchTolerance(options.matchTolerance); err != nil {
+		log.Printf("Invalid match tolerance: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err = checkClosureRule(options.closureRule); err != nil {
+		log.Printf("Invalid closure rule: %v\n", err)
+		os.Exit(1)
+	}
+
+	if polygonizer, err = newPolygonizer(polygonizerName, *polygonizerTimeout); err != nil {
+		log.Printf("Invalid polygonizer: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *mode == TRANSECTMODE {
+		var sceneDates []string
+		if sceneDates, err = parseDates(*dates); err != nil {
+			log.Printf("Invalid dates: %v\n", err)
+			os.Exit(1)
+		}
+		if transects.confidence <= 0 || transects.confidence >= 100 {
+			log.Printf("Invalid confidence %v; expected a percentage between 0 and 100\n", transects.confidence)
+			os.Exit(1)
+		}
+		if err = transectMain(args, *baselineCRS, *detectedCRS, sceneDates, *dateProperty, *filenameCSV, *anchorName, *seed, *seedPolarity, polygonizer, transects); err != nil {
+			log.Printf("Transect analysis failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *mode != REVIEWMODE && *mode != CHANGEMODE {
+		log.Printf("Unknown mode %v; expected %v, %v or %v\n", *mode, REVIEWMODE, CHANGEMODE, TRANSECTMODE)
+		os.Exit(1)
+	}
+
 	if len(args) > 2 {
 		filenameD = args[0]
 		filenameB = args[1]
@@ -47,29 +146,61 @@ func main() {
 	}
 
 	// Retrieve the detected features as a GeoJSON MultiLineString
-	if detected.geoJSON, err = geojson.ParseFile(filenameD); err != nil {
+	if detected, err = readScene(filenameD, *detectedCRS); err != nil {
 		log.Printf("File read error: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Retrieve the baseline features as a GeoJSON MultiLineString
-	if baseline.geoJSON, err = geojson.ParseFile(filenameB); err != nil {
+	if baseline, err = readScene(filenameB, *baselineCRS); err != nil {
 		log.Printf("File read error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err = baseline.clip(detected); err != nil {
-		log.Printf("Could not clip baseline: %v\n", err)
+	// Measure in meters by projecting both scenes into the UTM zone of the detected scene
+	if x, y, err = boundsCenter(detected.geoJSON); err != nil {
+		log.Printf("Could not find the center of the detected scene: %v\n", err)
+		os.Exit(1)
+	}
+	projection = newUTM(detected.coordinateSystem().ToGeographic(x, y))
+	if err = detected.reproject(projection); err != nil {
+		log.Printf("Could not project detected scene: %v\n", err)
+		os.Exit(1)
+	}
+	if err = baseline.reproject(projection); err != nil {
+		log.Printf("Could not project baseline scene: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Qualitative Review: What features match, are new, or are missing
-	if fc, err = qualitativeReview(detected, baseline); err != nil {
-		log.Printf("Qualitative Review failed: %v\n", err)
+	if *register {
+		if registration, err = registerScene(&detected, &baseline, options); err != nil {
+			log.Printf("Could not register detected scene: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *correct {
+		if bias, err = estimateBias(&detected, &baseline, options); err != nil {
+			log.Printf("Could not estimate bias of detected scene: %v\n", err)
+			os.Exit(1)
+		}
+		if bias == nil {
+			log.Printf("Could not estimate bias of detected scene: no features matched\n")
+			os.Exit(1)
+		}
+		if err = correctBias(&detected, bias); err != nil {
+			log.Printf("Could not correct bias of detected scene: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if anchor, err = newAnchor(*anchorName, *seed, *seedPolarity, projection); err != nil {
+		log.Printf("Invalid anchor: %v\n", err)
 		os.Exit(1)
 	}
-	if err = geojson.WriteFile(fc, filenameOut); err != nil {
-		log.Printf("Failed to write output of qualitative review: %v\n", err)
+
+	if err = baseline.clip(&detected); err != nil {
+		log.Printf("Could not clip baseline: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -79,13 +210,227 @@ func main() {
 		log.Printf("Could not retrieve envelope: %v\n", err)
 		os.Exit(1)
 	}
-	if err = quantitativeReview(baseline, detectedEnvelope); err != nil {
+	// The qualitative review does not need the quantitative review, so a review goes on without it
+	if baselineResult, err = quantitativeReview(baseline, detectedEnvelope, polygonizer, anchor); err != nil {
 		log.Printf("Quantitative review of baseline failed: %v\n", err)
-		os.Exit(1)
+		if *mode == CHANGEMODE {
+			os.Exit(1)
+		}
 	}
 
-	if err = quantitativeReview(detected, detectedEnvelope); err != nil {
+	if detectedResult, err = quantitativeReview(detected, detectedEnvelope, polygonizer, anchor); err != nil {
 		log.Printf("Quantitative review of detected failed: %v\n", err)
+		if *mode == CHANGEMODE {
+			os.Exit(1)
+		}
+	}
+
+	if *mode == REVIEWMODE {
+		// Qualitative Review: What features match, are new, or are missing
+		// The baseline land tells the qualitative review which way is seaward;
+		// without it cross-shore offsets are measured to the left of the baseline
+		var land *geos.Geometry
+		if !findsSea(anchor) {
+			log.Print("The first anchor may mistake water for land, so cross-shore offsets are measured to the left of the baseline; use -anchor edge or seed to measure them seaward\n")
+		} else if baselineResult != nil {
+			if land, err = landGeometry(baselineResult); err != nil {
+				log.Printf("Could not find baseline land: %v\n", err)
+			}
+		}
+		if fc, qualitativeResult, err = qualitativeReview(detected, baseline, land, options); err != nil {
+			log.Printf("Qualitative Review failed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *filenamePolygons != "" {
+		var polygons *geojson.FeatureCollection
+		if polygons, err = polygonCollection(baselineResult, detectedResult, projection); err != nil {
+			log.Printf("Could not create polygons: %v\n", err)
+			os.Exit(1)
+		}
+		if err = geojson.WriteFile(polygons, *filenamePolygons); err != nil {
+			log.Printf("Failed to write polygons of quantitative review: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	quantitative := make(map[string]*QuantitativeResult)
+	if baselineResult != nil {
+		quantitative["baseline"] = baselineResult
+	}
+	if detectedResult != nil {
+		quantitative["detected"] = detectedResult
+	}
+	properties := map[string]interface{}{
+		WORKINGCRS:   projection.String(),
+		QUANTITATIVE: quantitative}
+
+	if qualitativeResult != nil {
+		properties[QUALITATIVE] = qualitativeResult
+	}
+	if registration != nil {
+		properties[REGISTRATION] = registration
+	}
+	if bias != nil {
+		properties[BIASCORRECTION] = bias
+	}
+
+	if *mode == CHANGEMODE {
+		// Change Review: where land was gained or lost
+		if fc, changeResult, err = changeReview(baselineResult, detectedResult); err != nil {
+			log.Printf("Change review failed: %v\n", err)
+			os.Exit(1)
+		}
+		properties[CHANGE] = changeResult
+	}
+
+	// Return the output to longitude and latitude
+	if output, err = transformGeoJSON(fc, reprojectFunc(projection, geographic{})); err != nil {
+		log.Printf("Could not unproject output: %v\n", err)
+		os.Exit(1)
+	}
+	if err = writeReview(output.(*geojson.FeatureCollection), properties, filenameOut); err != nil {
+		log.Printf("Failed to write output of review: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// transectMain casts transects from a baseline and intersects them with detected scenes.
+// The arguments are the baseline file, the output file and one or more detected files.
+// The baseline is only a reference to cast transects from, as in DSAS, so it is not a shoreline,
+// but movement is measured from it if it is dated and only one dated shoreline crosses a transect.
+// The baseline and scenes are dated by the dates given, otherwise by their dateProperty or file names.
+func transectMain(args []string, baselineCRS, detectedCRS string, dates []string, dateProperty, filenameCSV, anchorName, seed, seedPolarity string, polygonizer Polygonizer, options transectOptions) error {
+	var (
+		baseline       Scene
+		detected       Scene
+		scenes         []transectScene
+		scene          transectScene
+		when           string
+		baselineDate   time.Time
+		mls            *geos.Geometry
+		envelope       *geos.Geometry
+		land           *geos.Geometry
+		anchor         Anchor
+		baselineResult *QuantitativeResult
+		fc             *geojson.FeatureCollection
+		output         interface{}
+		projection     utm
+		x, y           float64
+		err            error
+	)
+	if len(args) < 3 {
+		return fmt.Errorf("Expected a baseline file, an output file and at least one detected file")
+	}
+	if len(dates) > 0 && len(dates) != len(args)-1 {
+		return fmt.Errorf("Expected %v dates (the baseline and each detected scene), not %v", len(args)-1, len(dates))
+	}
+	// Shorelines are named by their files, so each may only be given once
+	named := make(map[string]bool)
+	for _, filename := range args[2:] {
+		if named[filename] {
+			return fmt.Errorf("Detected file %v is given more than once", filename)
+		}
+		named[filename] = true
+	}
+	date := func(inx int, filename string, scene *Scene) (string, error) {
+		if len(dates) == 0 {
+			return sceneDate(filename, scene, dateProperty)
+		}
+		return dates[inx], nil
+	}
+
+	if baseline, err = readScene(args[0], baselineCRS); err != nil {
+		return err
+	}
+	if when, err = date(0, args[0], &baseline); err != nil {
+		return err
+	}
+	if when != "" {
+		if baselineDate, err = time.Parse(dateLayout, when); err != nil {
+			return err
+		}
+	}
+	// Measure in meters by projecting every scene into the UTM zone of the baseline
+	if x, y, err = boundsCenter(baseline.geoJSON); err != nil {
+		return err
+	}
+	projection = newUTM(baseline.coordinateSystem().ToGeographic(x, y))
+	if err = baseline.reproject(projection); err != nil {
+		return err
+	}
+	for inx, filename := range args[2:] {
+		if detected, err = readScene(filename, detectedCRS); err != nil {
+			return err
+		}
+		if when, err = date(inx+1, filename, &detected); err != nil {
+			return err
+		}
+		if err = detected.reproject(projection); err != nil {
+			return err
+		}
+		if scene, err = newTransectScene(filename, when, &detected); err != nil {
+			return err
+		}
+		scenes = append(scenes, scene)
+	}
+
+	// The land/water polarity of the baseline points transects seaward
+	if anchor, err = newAnchor(anchorName, seed, seedPolarity, projection); err != nil {
+		return err
+	}
+	if envelope, err = baseline.envelope(); err != nil {
+		return err
+	}
+	if !findsSea(anchor) {
+		log.Print("The first anchor may mistake water for land, so transects point to the left of the baseline; use -anchor edge or seed to point them seaward\n")
+	} else if baselineResult, err = quantitativeReview(baseline, envelope, polygonizer, anchor); err != nil {
+		log.Printf("Transects will not be directed seaward: %v\n", err)
+	} else if land, err = landGeometry(baselineResult); err != nil {
+		return err
+	}
+
+	if mls, err = baseline.MultiLineString(); err != nil {
+		return err
+	}
+	if fc, err = transectReview(mls, baselineDate, scenes, land, options); err != nil {
+		return err
+	}
+	if filenameCSV != "" {
+		if err = writeTransectCSV(fc, filenameCSV); err != nil {
+			return err
+		}
+	}
+	// Return the output to longitude and latitude
+	if output, err = transformGeoJSON(fc, reprojectFunc(projection, geographic{})); err != nil {
+		return err
+	}
+	properties := map[string]interface{}{WORKINGCRS: projection.String(), SCENES: orderScenes(scenes), CONFIDENCE: options.confidence}
+	if !baselineDate.IsZero() {
+		properties[BASELINEDATE] = baselineDate.Format(dateLayout)
+	}
+	return writeReview(output.(*geojson.FeatureCollection), properties, args[1])
+}
+
+// parsePercentiles parses a comma-separated list of percentiles such as "90,95"
+func parsePercentiles(input string) ([]float64, error) {
+	var (
+		result     []float64
+		percentile float64
+		err        error
+	)
+	for _, part := range strings.Split(input, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		if percentile, err = strconv.ParseFloat(part, 64); err != nil {
+			return nil, err
+		}
+		if percentile <= 0 || percentile > 100 {
+			return nil, fmt.Errorf("Percentile %v is not in (0, 100]", percentile)
+		}
+		result = append(result, percentile)
+	}
+	return result, nil
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+
+	"github.com/paulsmith/gogeos/geos"
+)
+
+const (
+	// QUALITATIVE is the key for the FeatureCollection property containing
+	// the scene-level results of the qualitative review
+	QUALITATIVE = "qualitative"
+	// COMPLETENESS is the key for the GeoJSON property containing the fraction
+	// of the baseline length within tolerance of the detected shorelines
+	COMPLETENESS = "completeness"
+	// CORRECTNESS is the key for the GeoJSON property containing the fraction
+	// of the detected length within tolerance of the baseline shorelines
+	CORRECTNESS = "correctness"
+	// FSCORE is the key for the GeoJSON property containing the harmonic mean
+	// of completeness and correctness
+	FSCORE = "f_score"
+	// HAUSDORFF is the key for the GeoJSON property containing the directed
+	// and symmetric Hausdorff distances between matched features
+	HAUSDORFF = "hausdorff"
+	// hausdorffDensify is the fraction of each segment between the points Hausdorff distances are measured from
+	hausdorffDensify = 0.1
+	// FRECHET is the key for the GeoJSON property containing the discrete
+	// Fréchet distance between matched features
+	FRECHET = "frechet"
+)
+
+// QualitativeResult is the length-weighted agreement between the baseline and detected scenes
+type QualitativeResult struct {
+	Tolerance             float64    `json:"tolerance"`
+	BaselineLength        float64    `json:"baseline_length"`
+	BaselineLengthMatched float64    `json:"baseline_length_matched"`
+	DetectedLength        float64    `json:"detected_length"`
+	DetectedLengthMatched float64    `json:"detected_length_matched"`
+	Completeness          float64    `json:"completeness"`
+	Correctness           float64    `json:"correctness"`
+	FScore                float64    `json:"f_score"`
+	Bias                  *SceneBias `json:"bias,omitempty"`
+	Units                 string     `json:"units"`
+}
+
+// shorelineBuffers are the baseline and detected shorelines buffered by the tolerance
+type shorelineBuffers struct {
+	baseline, detected *geos.Geometry
+	// envelope is the envelope of the detected scene. Baseline features are clipped to it
+	// before they are measured, as the baseline scene is; if it is nil they are measured whole.
+	envelope *geos.Geometry
+}
+
+// newShorelineBuffers buffers the baseline and detected linework by the tolerance
+func newShorelineBuffers(baseline, detected, envelope *geos.Geometry, tolerance float64) (shorelineBuffers, error) {
+	var (
+		result = shorelineBuffers{envelope: envelope}
+		err    error
+	)
+	if result.baseline, err = baseline.Buffer(tolerance); err != nil {
+		return result, err
+	}
+	result.detected, err = detected.Buffer(tolerance)
+	return result, err
+}
+
+// lengthWithin returns the length of the input and the length of the input within the buffer
+func lengthWithin(input, buffer *geos.Geometry) (float64, float64, error) {
+	var (
+		total        float64
+		within       float64
+		intersection *geos.Geometry
+		err          error
+	)
+	if total, err = input.Length(); err != nil {
+		return 0, 0, err
+	}
+	if intersection, err = input.Intersection(buffer); err != nil {
+		return 0, 0, err
+	}
+	if within, err = intersection.Length(); err != nil {
+		return 0, 0, err
+	}
+	return total, within, nil
+}
+
+// clipToEnvelope returns the linework of the input within the envelope,
+// or the input itself if there is no envelope
+func clipToEnvelope(input, envelope *geos.Geometry) (*geos.Geometry, error) {
+	var (
+		result *geos.Geometry
+		err    error
+	)
+	if envelope == nil {
+		return input, nil
+	}
+	if result, err = envelope.Intersection(input); err != nil {
+		return nil, err
+	}
+	// Intersection can leave behind points where lines graze the envelope
+	return result.LineMerge()
+}
+
+// ratio returns numerator/denominator, or 0 when there is nothing to measure
+func ratio(numerator, denominator float64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+// fScore is the harmonic mean of completeness and correctness
+func fScore(completeness, correctness float64) float64 {
+	return ratio(2*completeness*correctness, completeness+correctness)
+}
+
+// addAgreement adds completeness (for a baseline geometry), correctness (for a detected geometry)
+// and, when both are given, the F-score to the properties. Either geometry may be nil.
+// Completeness only counts the baseline within the envelope of the buffers.
+func addAgreement(properties map[string]interface{}, baseline, detected *geos.Geometry, buffers shorelineBuffers) error {
+	var (
+		total, within             float64
+		completeness, correctness float64
+		err                       error
+	)
+	if baseline != nil {
+		if baseline, err = clipToEnvelope(baseline, buffers.envelope); err != nil {
+			return err
+		}
+		if total, within, err = lengthWithin(baseline, buffers.detected); err != nil {
+			return err
+		}
+		completeness = ratio(within, total)
+		properties[COMPLETENESS] = completeness
+	}
+	if detected != nil {
+		if total, within, err = lengthWithin(detected, buffers.baseline); err != nil {
+			return err
+		}
+		correctness = ratio(within, total)
+		properties[CORRECTNESS] = correctness
+	}
+	if baseline != nil && detected != nil {
+		properties[FSCORE] = fScore(completeness, correctness)
+	}
+	return nil
+}
+
+// sceneAgreement measures the agreement of all of the baseline and detected linework
+func sceneAgreement(baseline, detected *geos.Geometry, buffers shorelineBuffers, tolerance float64) (*QualitativeResult, error) {
+	var (
+		result = QualitativeResult{Tolerance: tolerance, Units: METERS}
+		err    error
+	)
+	if result.BaselineLength, result.BaselineLengthMatched, err = lengthWithin(baseline, buffers.detected); err != nil {
+		return nil, err
+	}
+	if result.DetectedLength, result.DetectedLengthMatched, err = lengthWithin(detected, buffers.baseline); err != nil {
+		return nil, err
+	}
+	result.Completeness = ratio(result.BaselineLengthMatched, result.BaselineLength)
+	result.Correctness = ratio(result.DetectedLengthMatched, result.DetectedLength)
+	result.FScore = fScore(result.Completeness, result.Correctness)
+	return &result, nil
+}
+
+// hausdorffDistances returns the directed Hausdorff distances in each direction and the symmetric distance.
+// Every segment is densified first, so the distance from the middle of a long segment counts
+// and not just the distances from the vertices of sparse lines.
+func hausdorffDistances(baseline, detected *geos.Geometry) (map[string]interface{}, error) {
+	var (
+		result = make(map[string]interface{})
+		detectedToBaseline,
+		baselineToDetected,
+		symmetric float64
+		err error
+	)
+	if detectedToBaseline, err = directedHausdorff(detected, baseline); err != nil {
+		return nil, err
+	}
+	if baselineToDetected, err = directedHausdorff(baseline, detected); err != nil {
+		return nil, err
+	}
+	if symmetric, err = baseline.HausdorffDistanceDensify(detected, hausdorffDensify); err != nil {
+		return nil, err
+	}
+	result["detected_to_baseline"] = detectedToBaseline
+	result["baseline_to_detected"] = baselineToDetected
+	result["symmetric"] = symmetric
+	result[UNITS] = METERS
+	return result, nil
+}
+
+// directedHausdorff is the largest distance from a point of the densified first line to the second line
+func directedHausdorff(from, to *geos.Geometry) (float64, error) {
+	var (
+		lines    [][]geos.Coord
+		point    *geos.Geometry
+		distance float64
+		result   float64
+		err      error
+	)
+	if lines, err = lineCoordArrays(from); err != nil {
+		return 0, err
+	}
+	for _, coords := range lines {
+		for _, coord := range densifyCoords(coords, hausdorffDensify) {
+			if point, err = geos.NewPoint(coord); err != nil {
+				return 0, err
+			}
+			if distance, err = point.Distance(to); err != nil {
+				return 0, err
+			}
+			result = math.Max(result, distance)
+		}
+	}
+	return result, nil
+}
+
+// densifyCoords splits every segment of a line into equal parts,
+// each the given fraction of the segment or less, as GEOS does
+func densifyCoords(input []geos.Coord, fraction float64) []geos.Coord {
+	if len(input) == 0 {
+		return nil
+	}
+	parts := int(math.Ceil(1 / fraction))
+	result := []geos.Coord{input[0]}
+	for inx := 1; inx < len(input); inx++ {
+		start, end := input[inx-1], input[inx]
+		for part := 1; part <= parts; part++ {
+			along := float64(part) / float64(parts)
+			result = append(result, geos.NewCoord(start.X+along*(end.X-start.X), start.Y+along*(end.Y-start.Y)))
+		}
+	}
+	return result
+}
+
+// frechetDistances returns the discrete Fréchet distance between two lines.
+// The fragments of a MultiLineString are taken in order as a single line.
+// Shorelines are not digitized in any particular direction
+// so the smaller of the distances to the line and its reverse is used.
+// If spacing is positive both lines are resampled at that spacing first.
+func frechetDistances(baseline, detected *geos.Geometry, spacing float64) (map[string]interface{}, error) {
+	var (
+		result         = make(map[string]interface{})
+		baselineCoords []geos.Coord
+		detectedCoords []geos.Coord
+		err            error
+	)
+	if baselineCoords, err = lineCoords(baseline, spacing); err != nil {
+		return nil, err
+	}
+	if detectedCoords, err = lineCoords(detected, spacing); err != nil {
+		return nil, err
+	}
+	reversed := make([]geos.Coord, len(detectedCoords))
+	for inx := range detectedCoords {
+		reversed[len(detectedCoords)-1-inx] = detectedCoords[inx]
+	}
+	result["discrete"] = math.Min(discreteFrechet(baselineCoords, detectedCoords), discreteFrechet(baselineCoords, reversed))
+	result[UNITS] = METERS
+	return result, nil
+}
+
+// discreteFrechet is the discrete Fréchet distance (Eiter and Mannila)
+// between two vertex sequences, computed a row at a time
+func discreteFrechet(first, second []geos.Coord) float64 {
+	if len(first) == 0 || len(second) == 0 {
+		return math.Inf(1)
+	}
+	previous := make([]float64, len(second))
+	current := make([]float64, len(second))
+	for inx := range first {
+		for jnx := range second {
+			distance := math.Hypot(first[inx].X-second[jnx].X, first[inx].Y-second[jnx].Y)
+			switch {
+			case inx == 0 && jnx == 0:
+				current[jnx] = distance
+			case inx == 0:
+				current[jnx] = math.Max(current[jnx-1], distance)
+			case jnx == 0:
+				current[jnx] = math.Max(previous[jnx], distance)
+			default:
+				current[jnx] = math.Max(math.Min(math.Min(previous[jnx], previous[jnx-1]), current[jnx-1]), distance)
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(second)-1]
+}
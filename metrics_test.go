@@ -0,0 +1,153 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+)
+
+// TestDiscreteFrechet checks the Fréchet distance of parallel and sparse lines
+func TestDiscreteFrechet(t *testing.T) {
+	line := []geos.Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}
+	parallel := []geos.Coord{{X: 0, Y: 1}, {X: 1, Y: 1}, {X: 2, Y: 1}}
+	sparse := []geos.Coord{{X: 0, Y: 1}, {X: 2, Y: 1}}
+	if distance := discreteFrechet(line, parallel); distance != 1 {
+		t.Errorf("Expected a distance of 1 for parallel lines, received %v", distance)
+	}
+	if distance := discreteFrechet(line, sparse); math.Abs(distance-math.Sqrt2) > 1e-12 {
+		t.Errorf("Expected a distance of %v for a sparse line, received %v", math.Sqrt2, distance)
+	}
+	if distance := discreteFrechet(line, nil); !math.IsInf(distance, 1) {
+		t.Errorf("Expected an infinite distance for an empty line, received %v", distance)
+	}
+}
+
+// TestSceneAgreement measures collinear lines that overlap for 50 meters
+func TestSceneAgreement(t *testing.T) {
+	var (
+		baseline, detected *geos.Geometry
+		buffers            shorelineBuffers
+		result             *QualitativeResult
+		err                error
+	)
+	if baseline, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if detected, err = geos.NewLineString(geos.NewCoord(50, 0), geos.NewCoord(250, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if buffers, err = newShorelineBuffers(baseline, detected, nil, 5); err != nil {
+		t.Fatal(err.Error())
+	}
+	if result, err = sceneAgreement(baseline, detected, buffers, 5); err != nil {
+		t.Fatal(err.Error())
+	}
+	// Each buffer reaches 5 meters past the end of its line
+	expected := QualitativeResult{
+		Tolerance:             5,
+		BaselineLength:        100,
+		BaselineLengthMatched: 55,
+		DetectedLength:        200,
+		DetectedLengthMatched: 55,
+		Completeness:          0.55,
+		Correctness:           0.275,
+		FScore:                2 * 0.55 * 0.275 / 0.825,
+		Units:                 METERS}
+	for name, values := range map[string][2]float64{
+		"baseline length":         {expected.BaselineLength, result.BaselineLength},
+		"baseline length matched": {expected.BaselineLengthMatched, result.BaselineLengthMatched},
+		"detected length":         {expected.DetectedLength, result.DetectedLength},
+		"detected length matched": {expected.DetectedLengthMatched, result.DetectedLengthMatched},
+		"completeness":            {expected.Completeness, result.Completeness},
+		"correctness":             {expected.Correctness, result.Correctness},
+		"F-score":                 {expected.FScore, result.FScore}} {
+		if math.Abs(values[0]-values[1]) > 1e-9 {
+			t.Errorf("Expected a %v of %v, received %v", name, values[0], values[1])
+		}
+	}
+
+	// A feature only has the measures for the geometries it has
+	properties := make(map[string]interface{})
+	if err = addAgreement(properties, nil, detected, buffers); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := properties[COMPLETENESS]; ok {
+		t.Error("Expected no completeness without a baseline")
+	}
+	if _, ok := properties[FSCORE]; ok {
+		t.Error("Expected no F-score without a baseline")
+	}
+	if correctness := properties[CORRECTNESS].(float64); math.Abs(correctness-0.275) > 1e-9 {
+		t.Errorf("Expected a correctness of 0.275, received %v", correctness)
+	}
+
+	// Only the baseline within the detected scene counts
+	clipped := buffers
+	clipped.envelope = rectangle(t, 50, -10, 250, 10)
+	properties = make(map[string]interface{})
+	if err = addAgreement(properties, baseline, detected, clipped); err != nil {
+		t.Fatal(err.Error())
+	}
+	if completeness := properties[COMPLETENESS].(float64); math.Abs(completeness-1) > 1e-9 {
+		t.Errorf("Expected a completeness of 1 within the detected scene, received %v", completeness)
+	}
+	if fScore(0, 0) != 0 {
+		t.Error("Expected an F-score of 0 when nothing agrees")
+	}
+}
+
+// TestHausdorffDistances measures from the middle of a sparse line, not just its vertices
+func TestHausdorffDistances(t *testing.T) {
+	var (
+		baseline, detected *geos.Geometry
+		result             map[string]interface{}
+		err                error
+	)
+	if baseline, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if detected, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(50, 10), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if result, err = hausdorffDistances(baseline, detected); err != nil {
+		t.Fatal(err.Error())
+	}
+	// The middle of the baseline is this far from either side of the detected peak
+	expected := 500 / math.Hypot(50, 10)
+	for key, value := range map[string]float64{"baseline_to_detected": expected, "detected_to_baseline": 10, "symmetric": 10} {
+		if distance := result[key].(float64); math.Abs(distance-value) > 1e-9 {
+			t.Errorf("Expected a %v distance of %v, received %v", key, value, distance)
+		}
+	}
+}
+
+// TestDensifyCoords splits each segment into equal parts
+func TestDensifyCoords(t *testing.T) {
+	coords := densifyCoords([]geos.Coord{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}}, 0.5)
+	expected := []geos.Coord{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 2.5}, {X: 10, Y: 5}}
+	if len(coords) != len(expected) {
+		t.Fatalf("Expected %v points, received %v", len(expected), len(coords))
+	}
+	for inx := range coords {
+		if coords[inx].X != expected[inx].X || coords[inx].Y != expected[inx].Y {
+			t.Errorf("Expected point %v to be %v, received %v", inx, expected[inx], coords[inx])
+		}
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+
+	"github.com/montanaflynn/stats"
+	"github.com/paulsmith/gogeos/geos"
+)
+
+const (
+	// OFFSET is the key for the GeoJSON property containing the signed cross-shore offsets
+	// of a detected feature from its baseline, positive seaward if that can be told
+	OFFSET = "cross_shore_offset"
+	// DIRECTION is the key for the GeoJSON property saying which way offsets are positive or transects point
+	DIRECTION = "direction"
+	// SEAWARD offsets are positive seaward
+	SEAWARD = "seaward"
+	// LEFT offsets are positive to the left of the baseline, as it was digitized
+	LEFT = "left"
+	// probeDistance is how far in meters to either side of a baseline the land is sampled
+	// to tell which side is seaward
+	probeDistance = 1.0
+	// probeCount is the most vertices of a baseline line at which the land is sampled
+	probeCount = 9
+)
+
+// crossing returns the signed distance along the direction from the origin to the nearest line
+// crossed by the ray from origin-maxDistance*direction to origin+maxDistance*direction.
+// The direction must be a unit vector. It returns false if the ray crosses no line.
+func (s segmentIndex) crossing(origin geos.Coord, dx, dy, maxDistance float64) (float64, bool) {
+	var (
+		result = math.Inf(1)
+		box    = bounds{minX: origin.X, minY: origin.Y, maxX: origin.X, maxY: origin.Y}
+	)
+	for _, inx := range s.tree.query(box.expand(maxDistance)) {
+		current := s.segments[inx]
+		ex, ey := current.end.X-current.start.X, current.end.Y-current.start.Y
+		denominator := dx*ey - dy*ex
+		if denominator == 0 {
+			continue
+		}
+		ax, ay := current.start.X-origin.X, current.start.Y-origin.Y
+		along := (ax*ey - ay*ex) / denominator
+		fraction := (ax*dy - ay*dx) / denominator
+		if fraction < 0 || fraction > 1 || math.Abs(along) > maxDistance {
+			continue
+		}
+		if math.Abs(along) < math.Abs(result) {
+			result = along
+		}
+	}
+	return result, !math.IsInf(result, 1)
+}
+
+// leftNormal returns the unit normal to the left of a line at one of its vertices
+func leftNormal(coords []geos.Coord, inx int) (float64, float64) {
+	previous, next := coords[maxInt(inx-1, 0)], coords[minInt(inx+1, len(coords)-1)]
+	dx, dy := next.X-previous.X, next.Y-previous.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return 0, 0
+	}
+	return -dy / length, dx / length
+}
+
+func maxInt(first, second int) int {
+	if first > second {
+		return first
+	}
+	return second
+}
+
+// seawardSign returns 1 if the sea is to the left of a line, -1 if it is to the right
+// or 0 if it cannot be told. The land on either side is sampled at a few vertices
+// and the side that is more often water wins.
+func seawardSign(coords []geos.Coord, land *geos.Geometry) (float64, error) {
+	var (
+		votes     int
+		leftLand  bool
+		rightLand bool
+		err       error
+		step      = maxInt(1, len(coords)/probeCount)
+		nx, ny    float64
+	)
+	for inx := step / 2; inx < len(coords); inx += step {
+		if nx, ny = leftNormal(coords, inx); nx == 0 && ny == 0 {
+			continue
+		}
+		if leftLand, err = landContains(land, coords[inx].X+probeDistance*nx, coords[inx].Y+probeDistance*ny); err != nil {
+			return 0, err
+		}
+		if rightLand, err = landContains(land, coords[inx].X-probeDistance*nx, coords[inx].Y-probeDistance*ny); err != nil {
+			return 0, err
+		}
+		switch {
+		case rightLand && !leftLand:
+			votes++
+		case leftLand && !rightLand:
+			votes--
+		}
+	}
+	switch {
+	case votes > 0:
+		return 1, nil
+	case votes < 0:
+		return -1, nil
+	}
+	return 0, nil
+}
+
+// landContains returns true if the point is on land
+func landContains(land *geos.Geometry, x, y float64) (bool, error) {
+	probe, err := geos.NewPoint(geos.NewCoord(x, y))
+	if err != nil {
+		return false, err
+	}
+	return land.Contains(probe)
+}
+
+// crossShoreOffsets measures the signed distance from each baseline vertex (or sample, if spacing is positive)
+// to the detected line along the baseline normal, positive seaward.
+// If there is no land, or the seaward side of any line of the baseline cannot be told from it,
+// every offset is positive to the left of the baseline instead, and the result's direction says so.
+// The profile gives the station of each offset along its line of the baseline and the index of that line.
+// It returns nil if the detected line crosses none of the normals.
+func crossShoreOffsets(baseline, detected, land *geos.Geometry, maxDistance float64, options qualitativeOptions) (map[string]interface{}, error) {
+	var (
+		baselineCoords [][]geos.Coord
+		detectedCoords [][]geos.Coord
+		offsets        stats.Float64Data
+		profile        []map[string]interface{}
+		station        float64
+		signs          []float64
+		direction      = SEAWARD
+		err            error
+		result         = make(map[string]interface{})
+	)
+	if baselineCoords, err = lineCoordArrays(baseline); err != nil {
+		return nil, err
+	}
+	if detectedCoords, err = lineCoordArrays(detected); err != nil {
+		return nil, err
+	}
+	signs = make([]float64, len(baselineCoords))
+	for line, coords := range baselineCoords {
+		if land == nil {
+			direction = LEFT
+			break
+		}
+		if signs[line], err = seawardSign(coords, land); err != nil {
+			return nil, err
+		}
+		if signs[line] == 0 {
+			direction = LEFT
+		}
+	}
+	if direction == LEFT {
+		for line := range signs {
+			signs[line] = 1
+		}
+	}
+	target := newSegmentIndex(detectedCoords)
+	for line, coords := range baselineCoords {
+		sign := signs[line]
+		if options.sampleSpacing > 0 {
+			coords = resampleCoords(coords, options.sampleSpacing)
+		}
+		// Stations are measured along each line of the baseline separately
+		station = 0
+		for inx, coord := range coords {
+			if inx > 0 {
+				station += math.Hypot(coord.X-coords[inx-1].X, coord.Y-coords[inx-1].Y)
+			}
+			nx, ny := leftNormal(coords, inx)
+			if nx == 0 && ny == 0 {
+				continue
+			}
+			if offset, ok := target.crossing(coord, sign*nx, sign*ny, maxDistance); ok {
+				offsets = append(offsets, offset)
+				profile = append(profile, map[string]interface{}{"line": line, "station": station, "offset": offset})
+			}
+		}
+	}
+	if len(offsets) == 0 {
+		return nil, nil
+	}
+	result[UNITS] = METERS
+	result[DIRECTION] = direction
+	if result["mean"], err = offsets.Mean(); err != nil {
+		return nil, err
+	}
+	if result["median"], err = offsets.Median(); err != nil {
+		return nil, err
+	}
+	if result["min"], err = offsets.Min(); err != nil {
+		return nil, err
+	}
+	if result["max"], err = offsets.Max(); err != nil {
+		return nil, err
+	}
+	if result["standard_deviation"], err = offsets.StandardDeviation(); err != nil {
+		return nil, err
+	}
+	result["profile"] = profile
+	return result, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// TestCrossing finds the nearest line crossed along a normal on either side
+func TestCrossing(t *testing.T) {
+	index := newSegmentIndex([][]geos.Coord{
+		{geos.NewCoord(0, 5), geos.NewCoord(10, 5)},
+		{geos.NewCoord(0, -3), geos.NewCoord(10, -3)}})
+	origin := geos.NewCoord(5, 0)
+	if offset, ok := index.crossing(origin, 0, 1, 10); !ok || offset != -3 {
+		t.Errorf("Expected an offset of -3, received %v (%v)", offset, ok)
+	}
+	if offset, ok := index.crossing(origin, 0, -1, 10); !ok || offset != 3 {
+		t.Errorf("Expected an offset of 3, received %v (%v)", offset, ok)
+	}
+	if offset, ok := index.crossing(origin, 0, 1, 2); ok {
+		t.Errorf("Expected no crossing within 2, received %v", offset)
+	}
+	if offset, ok := index.crossing(origin, 1, 0, 10); ok {
+		t.Errorf("Expected no crossing along the lines, received %v", offset)
+	}
+}
+
+// TestLeftNormal checks the normals of a bent line
+func TestLeftNormal(t *testing.T) {
+	coords := []geos.Coord{geos.NewCoord(0, 0), geos.NewCoord(10, 0), geos.NewCoord(10, 10)}
+	if x, y := leftNormal(coords, 0); x != 0 || y != 1 {
+		t.Errorf("Expected (0, 1) at the start, received (%v, %v)", x, y)
+	}
+	if x, y := leftNormal(coords, 2); x != -1 || y != 0 {
+		t.Errorf("Expected (-1, 0) at the end, received (%v, %v)", x, y)
+	}
+}
+
+// TestSeawardSign tells the seaward side of an eastward baseline from land to either side
+func TestSeawardSign(t *testing.T) {
+	coords := []geos.Coord{geos.NewCoord(0, 0), geos.NewCoord(100, 0)}
+	tests := []struct {
+		name     string
+		land     *geos.Geometry
+		expected float64
+	}{
+		{"land to the south", rectangle(t, -50, -50, 150, 0), 1},
+		{"land to the north", rectangle(t, -50, 0, 150, 50), -1},
+		{"land far away", rectangle(t, -50, 200, 150, 250), 0}}
+	for _, test := range tests {
+		sign, err := seawardSign(coords, test.land)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if sign != test.expected {
+			t.Errorf("Expected %v with %v, received %v", test.expected, test.name, sign)
+		}
+	}
+}
+
+// TestCrossShoreOffsets checks that offsets are positive seaward whichever side the land is on
+func TestCrossShoreOffsets(t *testing.T) {
+	var (
+		baseline, detected *geos.Geometry
+		offsets            map[string]interface{}
+		err                error
+	)
+	if baseline, err = toGeos(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {100, 0}}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	// The detected line is 5 meters north of the baseline
+	if detected, err = toGeos(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 5}, {100, 5}}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	tests := []struct {
+		name     string
+		land     *geos.Geometry
+		expected float64
+	}{
+		{"land to the south", rectangle(t, -50, -50, 150, 0), 5},
+		{"land to the north", rectangle(t, -50, 0, 150, 50), -5}}
+	for _, test := range tests {
+		if offsets, err = crossShoreOffsets(baseline, detected, test.land, 10, qualitativeOptions{}); err != nil {
+			t.Fatal(err.Error())
+		}
+		if offsets == nil {
+			t.Fatalf("Expected offsets with %v, received none", test.name)
+		}
+		for _, key := range []string{"mean", "min", "max"} {
+			if offsets[key] != test.expected {
+				t.Errorf("Expected a %v of %v with %v, received %v", key, test.expected, test.name, offsets[key])
+			}
+		}
+		if profile := offsets["profile"].([]map[string]interface{}); len(profile) != 2 || profile[1]["station"] != 100.0 {
+			t.Errorf("Expected offsets at stations 0 and 100 with %v, received %v", test.name, profile)
+		}
+		if offsets[DIRECTION] != SEAWARD {
+			t.Errorf("Expected seaward offsets with %v, received %v", test.name, offsets[DIRECTION])
+		}
+	}
+
+	// Without land to tell the seaward side, offsets are positive to the left of the baseline
+	for _, land := range []*geos.Geometry{nil, rectangle(t, -50, 200, 150, 250)} {
+		if offsets, err = crossShoreOffsets(baseline, detected, land, 10, qualitativeOptions{}); err != nil {
+			t.Fatal(err.Error())
+		}
+		if offsets[DIRECTION] != LEFT || offsets["mean"] != 5.0 {
+			t.Errorf("Expected a mean of 5 to the left with land %v, received %v", land, offsets)
+		}
+	}
+
+	// Each line of a baseline has its own stations
+	if baseline, err = toGeos(&geojson.MultiLineString{Type: geojson.MULTILINESTRING,
+		Coordinates: [][][]float64{{{0, 0}, {100, 0}}, {{200, 0}, {300, 0}}}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if detected, err = toGeos(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 5}, {300, 5}}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if offsets, err = crossShoreOffsets(baseline, detected, rectangle(t, -50, -50, 350, 0), 10, qualitativeOptions{}); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []map[string]interface{}{
+		{"line": 0, "station": 0.0, "offset": 5.0},
+		{"line": 0, "station": 100.0, "offset": 5.0},
+		{"line": 1, "station": 0.0, "offset": 5.0},
+		{"line": 1, "station": 100.0, "offset": 5.0}}
+	if profile := offsets["profile"].([]map[string]interface{}); !reflect.DeepEqual(expected, profile) {
+		t.Errorf("Expected %v, received %v", expected, profile)
+	}
+}
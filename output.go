@@ -0,0 +1,84 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// reviewCollection is a GeoJSON FeatureCollection that carries
+// the scene-level results of the review as a "properties" member
+type reviewCollection struct {
+	Type       string                 `json:"type"`
+	Features   []*geojson.Feature     `json:"features"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// writeReview writes the FeatureCollection and its scene-level properties to a file
+func writeReview(fc *geojson.FeatureCollection, properties map[string]interface{}, filename string) error {
+	var (
+		bytes []byte
+		err   error
+	)
+	rc := reviewCollection{Type: fc.Type, Features: fc.Features, Properties: properties}
+	if bytes, err = json.Marshal(rc); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, bytes, 0666)
+}
+
+// transectColumns are the transect properties written as CSV, in order
+var transectColumns = []string{TRANSECT, STATION, DIRECTION, SHORELINES, SCE, NSM, EPR, ECI, LRR, LCI, LR2}
+
+// writeTransectCSV writes the statistics of each transect to a CSV file,
+// leaving a cell empty when a transect lacks the statistic
+func writeTransectCSV(fc *geojson.FeatureCollection, filename string) error {
+	var (
+		file *os.File
+		err  error
+	)
+	if file, err = os.Create(filename); err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err = writer.Write(transectColumns); err != nil {
+		return err
+	}
+	for _, feature := range fc.Features {
+		record := make([]string, len(transectColumns))
+		for inx, column := range transectColumns {
+			if value, ok := feature.Properties[column]; ok {
+				record[inx] = fmt.Sprint(value)
+			}
+		}
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// TestWriteTransectCSV writes a row per transect with empty cells for missing statistics
+func TestWriteTransectCSV(t *testing.T) {
+	var (
+		dir     string
+		file    *os.File
+		records [][]string
+		err     error
+	)
+	if dir, err = ioutil.TempDir("", "transects"); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	line := &geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, -1}, {0, 1}}}
+	fc := geojson.NewFeatureCollection([]*geojson.Feature{
+		geojson.NewFeature(line, "", map[string]interface{}{
+			TRANSECT: 0, STATION: 0.0, DIRECTION: SEAWARD, SHORELINES: 2, SCE: 10.0, NSM: -10.0, EPR: -1.5, LRR: -1.5, UNITS: METERS}),
+		geojson.NewFeature(line, "", map[string]interface{}{TRANSECT: 1, STATION: 50.0, DIRECTION: LEFT, SHORELINES: 1})})
+	filename := filepath.Join(dir, "transects.csv")
+	if err = writeTransectCSV(fc, filename); err != nil {
+		t.Fatal(err.Error())
+	}
+	if file, err = os.Open(filename); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer file.Close()
+	if records, err = csv.NewReader(file).ReadAll(); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := [][]string{
+		{"transect", "station", "direction", "shorelines", "sce", "nsm", "epr", "eci", "lrr", "lci", "lr2"},
+		{"0", "0", "seaward", "2", "10", "-10", "-1.5", "", "-1.5", "", ""},
+		{"1", "50", "left", "1", "", "", "", "", "", "", ""}}
+	if !reflect.DeepEqual(expected, records) {
+		t.Errorf("Expected %v, received %v", expected, records)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+/*
+#cgo LDFLAGS: -lgeos_c
+#include <stdlib.h>
+#include <string.h>
+#include <geos_c.h>
+
+#define BF_MESSAGE_SIZE 256
+
+static void bf_error(const char *message, void *userdata) {
+	strncpy((char *)userdata, message, BF_MESSAGE_SIZE - 1);
+}
+
+// bf_polygonize polygonizes the linework of a WKB geometry.
+// The result is WKB allocated with malloc; NULL is returned on failure
+// with the GEOS error (if any) copied to message.
+static unsigned char *bf_polygonize(const unsigned char *wkb, size_t size, size_t *resultSize, char *message) {
+	GEOSContextHandle_t handle;
+	GEOSGeometry *input, *output;
+	const GEOSGeometry *geoms[1];
+	unsigned char *buffer, *result = NULL;
+
+	if ((handle = GEOS_init_r()) == NULL) {
+		return NULL;
+	}
+	GEOSContext_setErrorMessageHandler_r(handle, bf_error, message);
+	if ((input = GEOSGeomFromWKB_buf_r(handle, wkb, size)) != NULL) {
+		geoms[0] = input;
+		if ((output = GEOSPolygonize_r(handle, geoms, 1)) != NULL) {
+			if ((buffer = GEOSGeomToWKB_buf_r(handle, output, resultSize)) != NULL) {
+				if ((result = malloc(*resultSize)) != NULL) {
+					memcpy(result, buffer, *resultSize);
+				}
+				GEOSFree_r(handle, buffer);
+			}
+			GEOSGeom_destroy_r(handle, output);
+		}
+		GEOSGeom_destroy_r(handle, input);
+	}
+	GEOS_finish_r(handle);
+	return result;
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/paulsmith/gogeos/geos"
+)
+
+// polygonize returns a GeometryCollection of the polygons formed by the input linework.
+// The linework must already be noded; gogeos does not expose GEOSPolygonize
+// so the geometry is passed to GEOS directly as WKB.
+func polygonize(input *geos.Geometry) (*geos.Geometry, error) {
+	var (
+		wkb        []byte
+		resultSize C.size_t
+		err        error
+	)
+	if wkb, err = input.ToWKB(); err != nil {
+		return nil, err
+	}
+	if len(wkb) == 0 {
+		return nil, errors.New("Cannot polygonize an empty geometry")
+	}
+	message := (*C.char)(C.calloc(C.BF_MESSAGE_SIZE, 1))
+	defer C.free(unsafe.Pointer(message))
+
+	result := C.bf_polygonize((*C.uchar)(unsafe.Pointer(&wkb[0])), C.size_t(len(wkb)), &resultSize, message)
+	if result == nil {
+		return nil, fmt.Errorf("GEOS Polygonize failed: %v", C.GoString(message))
+	}
+	defer C.free(unsafe.Pointer(result))
+	return geos.FromWKB(C.GoBytes(unsafe.Pointer(result), C.int(resultSize)))
+}
+
+// Polygonizer turns a slice of LineStrings into a MultiPolygon
+type Polygonizer interface {
+	Polygonize(input []*geos.Geometry) (*geos.Geometry, error)
+}
+
+const (
+	// GEOSPOLYGONIZER is the name of the in-process GEOS Polygonizer
+	GEOSPOLYGONIZER = "geos"
+	// BFLAPOLYGONIZER is the name of the Polygonizer that calls bf-line-analyzer
+	BFLAPOLYGONIZER = "bf_la"
+)
+
+// newPolygonizer returns the Polygonizer with the given name
+func newPolygonizer(name string, timeout time.Duration) (Polygonizer, error) {
+	switch name {
+	case GEOSPOLYGONIZER:
+		return geosPolygonizer{}, nil
+	case BFLAPOLYGONIZER:
+		return bflaPolygonizer{
+			path:    filepath.Join(os.Getenv("BF_LINE_ANALYZER_DIR"), "bld", "bf_la"),
+			timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("Unknown polygonizer %v; expected %v or %v", name, GEOSPOLYGONIZER, BFLAPOLYGONIZER)
+	}
+}
+
+// geosPolygonizer polygonizes in-process using GEOS
+type geosPolygonizer struct{}
+
+// Polygonize nodes the linework and polygonizes it with GEOS
+func (p geosPolygonizer) Polygonize(input []*geos.Geometry) (*geos.Geometry, error) {
+	var (
+		mls        *geos.Geometry
+		noded      *geos.Geometry
+		collection *geos.Geometry
+		polygons   []*geos.Geometry
+		err        error
+	)
+	if mls, err = geos.NewCollection(geos.MULTILINESTRING, input[:]...); err != nil {
+		return nil, err
+	}
+	// Node the linework so every intersection becomes a vertex
+	if noded, err = mls.UnaryUnion(); err != nil {
+		return nil, err
+	}
+	if collection, err = polygonize(noded); err != nil {
+		return nil, err
+	}
+	// GEOS returns a GeometryCollection but we want a MultiPolygon
+	if polygons, err = components(collection); err != nil {
+		return nil, err
+	}
+	return geos.NewCollection(geos.MULTIPOLYGON, polygons...)
+}
+
+// bflaPolygonizer polygonizes by calling bf-line-analyzer,
+// which is useful for cross-checking the GEOS implementation
+type bflaPolygonizer struct {
+	path    string
+	timeout time.Duration
+}
+
+// Polygonize streams the linework to bf_la as WKT and reads the MultiPolygon back
+func (p bflaPolygonizer) Polygonize(input []*geos.Geometry) (*geos.Geometry, error) {
+	var (
+		mls            *geos.Geometry
+		err            error
+		geometryString string
+		stdout         bytes.Buffer
+		stderr         bytes.Buffer
+	)
+
+	// Take the input, turn it into a MultiLineString so we can pass it to C++-land
+	if mls, err = geos.NewCollection(geos.MULTILINESTRING, input[:]...); err != nil {
+		return nil, err
+	}
+	if geometryString, err = mls.ToWKT(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	// bf_la expects a file name so hand it standard input
+	cmd := exec.CommandContext(ctx, p.path, "-mlp", "/dev/stdin")
+	cmd.Stdin = strings.NewReader(geometryString)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%v timed out after %v: %v", p.path, p.timeout, stderr.String())
+		}
+		return nil, fmt.Errorf("%v failed: %v: %v", p.path, err, stderr.String())
+	}
+	return geos.FromWKT(stdout.String())
+}
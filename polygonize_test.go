@@ -0,0 +1,97 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+)
+
+// TestGeosPolygonizer splits an envelope with a chord and ignores a dangle
+func TestGeosPolygonizer(t *testing.T) {
+	var (
+		chords  []*geos.Geometry
+		chord   *geos.Geometry
+		result  *geos.Geometry
+		polygon *geos.Geometry
+		gType   geos.GeometryType
+		count   int
+		area    float64
+		err     error
+	)
+	lines := [][]geos.Coord{
+		{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}, {X: 0, Y: 0}},
+		{{X: 0, Y: 5}, {X: 10, Y: 5}},
+		{{X: 5, Y: 0}, {X: 5, Y: 2}}}
+	for _, line := range lines {
+		if chord, err = geos.NewLineString(line...); err != nil {
+			t.Fatal(err.Error())
+		}
+		chords = append(chords, chord)
+	}
+	if result, err = (geosPolygonizer{}).Polygonize(chords); err != nil {
+		t.Fatalf("Failed to polygonize: %v", err)
+	}
+	if gType, _ = result.Type(); gType != geos.MULTIPOLYGON {
+		t.Errorf("Expected MultiPolygon, received %v", gType)
+	}
+	if count, _ = result.NGeometry(); count != 2 {
+		t.Fatalf("Expected 2 polygons, received %v", count)
+	}
+	for inx := 0; inx < count; inx++ {
+		polygon, _ = result.Geometry(inx)
+		if area, _ = polygon.Area(); area != 50 {
+			t.Errorf("Expected polygon %v to have an area of 50, received %v", inx, area)
+		}
+	}
+}
+
+// TestBflaPolygonizer streams the linework to a stand-in for bf_la that echoes its input
+func TestBflaPolygonizer(t *testing.T) {
+	var (
+		dir    string
+		line   *geos.Geometry
+		result *geos.Geometry
+		gType  geos.GeometryType
+		err    error
+	)
+	if runtime.GOOS == "windows" {
+		t.Skip("The stand-in for bf_la is a shell script")
+	}
+	if dir, err = ioutil.TempDir("", "bf_la"); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bf_la")
+	if err = ioutil.WriteFile(path, []byte("#!/bin/sh\ncat \"$2\"\n"), 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	if line, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(1, 1)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if result, err = (bflaPolygonizer{path: path}).Polygonize([]*geos.Geometry{line}); err != nil {
+		t.Fatalf("Failed to polygonize: %v", err)
+	}
+	if gType, _ = result.Type(); gType != geos.MULTILINESTRING {
+		t.Errorf("Expected bf_la to read back the MultiLineString, received %v", gType)
+	}
+}
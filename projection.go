@@ -0,0 +1,253 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+const (
+	// UNITS is the key for the property naming the units of a measurement
+	UNITS = "units"
+	// METERS are the units of distances
+	METERS = "m"
+	// SQUAREMETERS are the units of areas
+	SQUAREMETERS = "m²"
+	// WORKINGCRS is the key for the FeatureCollection property naming
+	// the CRS the measurements were made in
+	WORKINGCRS = "working_crs"
+)
+
+// WGS84 ellipsoid and UTM constants
+const (
+	wgs84A       = 6378137.0
+	wgs84F       = 1 / 298.257223563
+	utmK0        = 0.9996
+	utmEasting   = 500000.0
+	utmNorthingS = 10000000.0
+)
+
+// utm is a Universal Transverse Mercator zone on the WGS84 ellipsoid.
+// It uses the Krüger series, which is accurate to well under a millimeter within a zone.
+type utm struct {
+	zone  int
+	south bool
+}
+
+// newUTM returns the UTM zone containing the given longitude and latitude
+func newUTM(lon, lat float64) utm {
+	zone := int(math.Floor((lon+180)/6)) + 1
+	if zone < 1 {
+		zone = 1
+	} else if zone > 60 {
+		zone = 60
+	}
+	return utm{zone: zone, south: lat < 0}
+}
+
+// EPSG returns the EPSG code of the zone
+func (u utm) EPSG() int {
+	if u.south {
+		return 32700 + u.zone
+	}
+	return 32600 + u.zone
+}
+
+// String returns the zone as an EPSG identifier
+func (u utm) String() string {
+	return fmt.Sprintf("EPSG:%d", u.EPSG())
+}
+
+func (u utm) centralMeridian() float64 {
+	return float64(6*u.zone-183) * math.Pi / 180
+}
+
+func (u utm) falseNorthing() float64 {
+	if u.south {
+		return utmNorthingS
+	}
+	return 0
+}
+
+// krugerSeries returns the rectifying radius and the series coefficients
+func krugerSeries() (float64, [4]float64, [4]float64, [4]float64) {
+	n := wgs84F / (2 - wgs84F)
+	n2, n3, n4 := n*n, n*n*n, n*n*n*n
+	a := wgs84A / (1 + n) * (1 + n2/4 + n4/64)
+	alpha := [4]float64{
+		n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180,
+		13*n2/48 - 3*n3/5 + 557*n4/1440,
+		61*n3/240 - 103*n4/140,
+		49561 * n4 / 161280}
+	beta := [4]float64{
+		n/2 - 2*n2/3 + 37*n3/96 - n4/360,
+		n2/48 + n3/15 - 437*n4/1440,
+		17*n3/480 - 37*n4/840,
+		4397 * n4 / 161280}
+	delta := [4]float64{
+		2*n - 2*n2/3 - 2*n3 + 116*n4/45,
+		7*n2/3 - 8*n3/5 - 227*n4/45,
+		56*n3/15 - 136*n4/35,
+		4279 * n4 / 630}
+	return a, alpha, beta, delta
+}
+
+// FromGeographic projects a longitude and latitude (degrees) to an easting and northing (meters)
+func (u utm) FromGeographic(lon, lat float64) (float64, float64) {
+	a, alpha, _, _ := krugerSeries()
+	e := math.Sqrt(wgs84F * (2 - wgs84F))
+	phi := lat * math.Pi / 180
+	lambda := lon*math.Pi/180 - u.centralMeridian()
+
+	t := math.Sinh(math.Atanh(math.Sin(phi)) - e*math.Atanh(e*math.Sin(phi)))
+	xi := math.Atan2(t, math.Cos(lambda))
+	eta := math.Atanh(math.Sin(lambda) / math.Sqrt(1+t*t))
+
+	easting, northing := eta, xi
+	for j := 0; j < 4; j++ {
+		k := float64(2 * (j + 1))
+		easting += alpha[j] * math.Cos(k*xi) * math.Sinh(k*eta)
+		northing += alpha[j] * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+	return utmEasting + utmK0*a*easting, u.falseNorthing() + utmK0*a*northing
+}
+
+// ToGeographic unprojects an easting and northing (meters) to a longitude and latitude (degrees)
+func (u utm) ToGeographic(easting, northing float64) (float64, float64) {
+	a, _, beta, delta := krugerSeries()
+	xi := (northing - u.falseNorthing()) / (utmK0 * a)
+	eta := (easting - utmEasting) / (utmK0 * a)
+
+	xiPrime, etaPrime := xi, eta
+	for j := 0; j < 4; j++ {
+		k := float64(2 * (j + 1))
+		xiPrime -= beta[j] * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaPrime -= beta[j] * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	chi := math.Asin(math.Sin(xiPrime) / math.Cosh(etaPrime))
+	phi := chi
+	for j := 0; j < 4; j++ {
+		phi += delta[j] * math.Sin(float64(2*(j+1))*chi)
+	}
+	lambda := math.Atan2(math.Sinh(etaPrime), math.Cos(xiPrime))
+	return (lambda + u.centralMeridian()) * 180 / math.Pi, phi * 180 / math.Pi
+}
+
+// transformFunc transforms a single coordinate
+type transformFunc func(x, y float64) (float64, float64)
+
+func transformCoord(input []float64, transform transformFunc) []float64 {
+	result := make([]float64, len(input))
+	copy(result, input)
+	if len(input) > 1 {
+		result[0], result[1] = transform(input[0], input[1])
+	}
+	return result
+}
+func transformCoordArray(input [][]float64, transform transformFunc) [][]float64 {
+	var result [][]float64
+	for inx := 0; inx < len(input); inx++ {
+		result = append(result, transformCoord(input[inx], transform))
+	}
+	return result
+}
+func transformCoordArrays(input [][][]float64, transform transformFunc) [][][]float64 {
+	var result [][][]float64
+	for inx := 0; inx < len(input); inx++ {
+		result = append(result, transformCoordArray(input[inx], transform))
+	}
+	return result
+}
+
+// transformGeoJSON returns a copy of a GeoJSON object with every coordinate transformed.
+// The input is never modified because output features share geometries with their inputs.
+func transformGeoJSON(input interface{}, transform transformFunc) (interface{}, error) {
+	var err error
+	switch gt := input.(type) {
+	case *geojson.Point:
+		return &geojson.Point{Type: gt.Type, Coordinates: transformCoord(gt.Coordinates, transform)}, nil
+	case *geojson.LineString:
+		return &geojson.LineString{Type: gt.Type, Coordinates: transformCoordArray(gt.Coordinates, transform)}, nil
+	case *geojson.Polygon:
+		return &geojson.Polygon{Type: gt.Type, Coordinates: transformCoordArrays(gt.Coordinates, transform)}, nil
+	case *geojson.MultiPoint:
+		return &geojson.MultiPoint{Type: gt.Type, Coordinates: transformCoordArray(gt.Coordinates, transform)}, nil
+	case *geojson.MultiLineString:
+		return &geojson.MultiLineString{Type: gt.Type, Coordinates: transformCoordArrays(gt.Coordinates, transform)}, nil
+	case *geojson.MultiPolygon:
+		var coordinates [][][][]float64
+		for inx := 0; inx < len(gt.Coordinates); inx++ {
+			coordinates = append(coordinates, transformCoordArrays(gt.Coordinates[inx], transform))
+		}
+		return &geojson.MultiPolygon{Type: gt.Type, Coordinates: coordinates}, nil
+	case *geojson.GeometryCollection:
+		result := &geojson.GeometryCollection{Type: gt.Type}
+		for _, geometry := range gt.Geometries {
+			if geometry, err = transformGeoJSON(geometry, transform); err != nil {
+				return nil, err
+			}
+			result.Geometries = append(result.Geometries, geometry)
+		}
+		return result, nil
+	case *geojson.Feature:
+		result := *gt
+		if gt.Geometry != nil {
+			if result.Geometry, err = transformGeoJSON(gt.Geometry, transform); err != nil {
+				return nil, err
+			}
+		}
+		return &result, nil
+	case *geojson.FeatureCollection:
+		var feature interface{}
+		result := *gt
+		result.Features = nil
+		for _, current := range gt.Features {
+			if feature, err = transformGeoJSON(current, transform); err != nil {
+				return nil, err
+			}
+			result.Features = append(result.Features, feature.(*geojson.Feature))
+		}
+		return &result, nil
+	default:
+		return nil, fmt.Errorf("Unexpected type in transformGeoJSON: %T", gt)
+	}
+}
+
+// boundsCenter returns the center of the bounding box of a GeoJSON object
+func boundsCenter(input interface{}) (float64, float64, error) {
+	var (
+		minX, minY = math.Inf(1), math.Inf(1)
+		maxX, maxY = math.Inf(-1), math.Inf(-1)
+		err        error
+	)
+	visit := func(x, y float64) (float64, float64) {
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		return x, y
+	}
+	if _, err = transformGeoJSON(input, visit); err != nil {
+		return 0, 0, err
+	}
+	if math.IsInf(minX, 1) {
+		return 0, 0, errors.New("Cannot find the center of a GeoJSON object without coordinates")
+	}
+	return (minX + maxX) / 2, (minY + maxY) / 2, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// TestUTM projects known points and makes sure they come back again
+func TestUTM(t *testing.T) {
+	tests := []struct {
+		lon, lat, easting, northing float64
+		epsg                        int
+	}{
+		{3, 0, 500000, 0, 32631},
+		{-77, 40, 329274.5, 4429673.0, 32618},
+		{115.7, -32.29, 377591.6, 6426677.3, 32750}}
+	for _, test := range tests {
+		projection := newUTM(test.lon, test.lat)
+		if projection.EPSG() != test.epsg {
+			t.Errorf("Expected EPSG:%v for %v,%v, received %v", test.epsg, test.lon, test.lat, projection)
+		}
+		easting, northing := projection.FromGeographic(test.lon, test.lat)
+		if math.Abs(easting-test.easting) > 1 || math.Abs(northing-test.northing) > 1 {
+			t.Errorf("Expected %v,%v to project to %v,%v, received %v,%v", test.lon, test.lat, test.easting, test.northing, easting, northing)
+		}
+		lon, lat := projection.ToGeographic(easting, northing)
+		if math.Abs(lon-test.lon) > 1e-9 || math.Abs(lat-test.lat) > 1e-9 {
+			t.Errorf("Expected %v,%v to round trip, received %v,%v", test.lon, test.lat, lon, lat)
+		}
+	}
+}
+
+// TestParseCRS recognizes the common ways of naming supported CRSs
+func TestParseCRS(t *testing.T) {
+	tests := map[string]string{
+		"urn:ogc:def:crs:OGC:1.3:CRS84":               CRS84,
+		"urn:ogc:def:crs:EPSG::4326":                  CRS84,
+		"urn:ogc:def:crs:EPSG:6.6:4326":               CRS84,
+		"EPSG:3857":                                   "EPSG:3857",
+		"urn:ogc:def:crs:EPSG::32750":                 "EPSG:32750",
+		"http://www.opengis.net/def/crs/EPSG/0/32618": "EPSG:32618"}
+	for name, expected := range tests {
+		crs, err := parseCRS(name)
+		if err != nil {
+			t.Errorf("Failed to parse %v: %v", name, err)
+			continue
+		}
+		if crs.String() != expected {
+			t.Errorf("Expected %v to be %v, received %v", name, expected, crs)
+		}
+	}
+	for _, name := range []string{"EPSG:27700", "urn:ogc:def:crs:OGC:1.3:CRS27", ""} {
+		if _, err := parseCRS(name); err == nil {
+			t.Errorf("Expected %v to be unsupported", name)
+		}
+	}
+}
+
+// TestCRSMember reads the crs member of GeoJSON documents
+func TestCRSMember(t *testing.T) {
+	tests := map[string]string{
+		`{"type":"FeatureCollection","features":[]}`:                                                          "",
+		`{"type":"FeatureCollection","crs":{"type":"name","properties":{"name":"EPSG:32618"}},"features":[]}`: "EPSG:32618"}
+	for document, expected := range tests {
+		name, err := crsMember([]byte(document))
+		if err != nil || name != expected {
+			t.Errorf("Expected %v, received %v, %v", expected, name, err)
+		}
+	}
+	if _, err := crsMember([]byte(`{"crs":{"type":"link","properties":{"href":"crs.wkt"}}}`)); err == nil {
+		t.Error("Expected linked CRSs to be unsupported")
+	}
+}
+
+// TestWebMercator projects a known point and makes sure it comes back again
+func TestWebMercator(t *testing.T) {
+	x, y := webMercator{}.FromGeographic(-77, 40)
+	if math.Abs(x+8571600.79) > 0.01 || math.Abs(y-4865942.28) > 0.01 {
+		t.Errorf("Expected -77,40 to project to -8571600.79,4865942.28, received %v,%v", x, y)
+	}
+	if lon, lat := (webMercator{}).ToGeographic(x, y); math.Abs(lon+77) > 1e-9 || math.Abs(lat-40) > 1e-9 {
+		t.Errorf("Expected -77,40 to round trip, received %v,%v", lon, lat)
+	}
+}
+
+// TestTransformGeoJSON moves every coordinate of a copy of a FeatureCollection
+func TestTransformGeoJSON(t *testing.T) {
+	var (
+		output interface{}
+		err    error
+	)
+	shift := func(x, y float64) (float64, float64) { return x + 1, 2 * y }
+	input := geojson.NewFeatureCollection([]*geojson.Feature{
+		geojson.NewFeature(&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}},
+			"polygon", map[string]interface{}{"name": "polygon"}),
+		geojson.NewFeature(&geojson.GeometryCollection{Type: geojson.GEOMETRYCOLLECTION, Geometries: []interface{}{
+			&geojson.Point{Type: geojson.POINT, Coordinates: []float64{1, 2, 3}},
+			&geojson.MultiLineString{Type: geojson.MULTILINESTRING, Coordinates: [][][]float64{{{0, 1}, {2, 3}}}}}},
+			"collection", map[string]interface{}{})})
+	if output, err = transformGeoJSON(input, shift); err != nil {
+		t.Fatal(err.Error())
+	}
+	features := output.(*geojson.FeatureCollection).Features
+	if len(features) != 2 || features[0].Properties["name"] != "polygon" {
+		t.Fatalf("Expected the features and their properties to be kept, received %v", features)
+	}
+	if expected, result := [][][]float64{{{1, 0}, {2, 0}, {2, 2}, {1, 0}}}, features[0].Geometry.(*geojson.Polygon).Coordinates; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, received %v", expected, result)
+	}
+	geometries := features[1].Geometry.(*geojson.GeometryCollection).Geometries
+	// The third ordinate is left alone
+	if expected, result := []float64{2, 4, 3}, geometries[0].(*geojson.Point).Coordinates; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, received %v", expected, result)
+	}
+	if expected, result := [][][]float64{{{1, 2}, {3, 6}}}, geometries[1].(*geojson.MultiLineString).Coordinates; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, received %v", expected, result)
+	}
+	if expected, result := [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, input.Features[0].Geometry.(*geojson.Polygon).Coordinates; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected the input to be left alone, received %v", result)
+	}
+	if _, err = transformGeoJSON("not GeoJSON", shift); err == nil {
+		t.Error("Expected an error for something that is not GeoJSON")
+	}
+}
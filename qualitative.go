@@ -17,6 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+
 	"github.com/montanaflynn/stats"
 	"github.com/paulsmith/gogeos/geos"
 	"github.com/venicegeo/geojson-go/geojson"
@@ -35,9 +39,65 @@ const (
 	// DETECTIONBIAS is the key for the GeoJSON property indicating the bias
 	// detected between the detected and baseline features
 	DETECTIONBIAS = "detection_bias"
+	// MATCHDISTANCE is the key for the GeoJSON property containing the shortest
+	// distance between matched baseline and detected features
+	MATCHDISTANCE = "match_distance"
+	// FRAGMENTS is the key for the GeoJSON property describing the fragments
+	// of a feature that was matched in several pieces
+	FRAGMENTS = "fragments"
+	// LENGTH is the key for the GeoJSON property containing the length of a segment
+	LENGTH = "length"
+	// DETECTEDLENGTH is the key for the GeoJSON property containing the length
+	// of a matched baseline feature within the match tolerance of its detected feature
+	DETECTEDLENGTH = "detected_length"
+	// UNDETECTEDLENGTH is the key for the GeoJSON property containing the length
+	// of a matched baseline feature outside the match tolerance of its detected feature
+	UNDETECTEDLENGTH = "undetected_length"
+	// maxHistogramBins is the most bins a distance histogram may have
+	maxHistogramBins = 10000
 )
 
-func measureDisplacement(baseline, detected *geos.Geometry) (map[string]interface{}, error) {
+// qualitativeOptions controls the measurements made by the qualitative review
+type qualitativeOptions struct {
+	// tolerance is the distance in meters within which shorelines agree
+	tolerance float64
+	// matchTolerance is the distance in meters within which features can match,
+	// or 0 if they must intersect
+	matchTolerance float64
+	// closureRule decides whether open and closed lines can match
+	closureRule string
+	// edgeTolerance is the distance in meters from the edge of the scene
+	// within which a line counts as clipped
+	edgeTolerance float64
+	// maxOffset is the farthest in meters along the baseline normal to look for the detected line
+	maxOffset float64
+	// percentiles of the distances to report
+	percentiles []float64
+	// histogramWidth is the width in meters of histogram bins, or 0 for no histogram
+	histogramWidth float64
+	// sampleSpacing is the distance in meters between the points distances are measured from,
+	// or 0 to measure from the vertices
+	sampleSpacing float64
+}
+
+// segmentDistance is the distance in meters within which a stretch of a matched
+// baseline feature counts as detected: the match tolerance or, if that is 0, the tolerance
+func (options qualitativeOptions) segmentDistance() float64 {
+	if options.matchTolerance > 0 {
+		return options.matchTolerance
+	}
+	return options.tolerance
+}
+
+// checkMatchTolerance returns an error if the match tolerance is negative
+func checkMatchTolerance(matchTolerance float64) error {
+	if matchTolerance < 0 {
+		return fmt.Errorf("Match tolerance %v is negative; expected 0 or a positive distance", matchTolerance)
+	}
+	return nil
+}
+
+func measureDisplacement(baseline, detected *geos.Geometry, options qualitativeOptions) (map[string]interface{}, error) {
 	var (
 		northingBias float64
 		eastingBias  float64
@@ -59,175 +119,588 @@ func measureDisplacement(baseline, detected *geos.Geometry) (map[string]interfac
 	eastingBias = dcx - bcx
 	biasMap["northing"] = northingBias
 	biasMap["easting"] = eastingBias
+	biasMap[UNITS] = METERS
 
 	// Correct for bias by displacing in the opposite direction
 	if detected, err = displace(detected, -eastingBias, -northingBias); err != nil {
 		return nil, err
 	}
-	if data, err = lineStringsToFloat64Data(detected, baseline); err != nil {
+	if data, err = lineStringsToFloat64Data(detected, baseline, options.sampleSpacing); err != nil {
 		return nil, err
 	}
-	if biasMap[DETECTEDSTATS], err = populateStatistics(data); err != nil {
+	if biasMap[DETECTEDSTATS], err = populateStatistics(data, options); err != nil {
 		return nil, err
 	}
-	if data, err = lineStringsToFloat64Data(baseline, detected); err != nil {
+	if data, err = lineStringsToFloat64Data(baseline, detected, options.sampleSpacing); err != nil {
 		return nil, err
 	}
-	if biasMap[BASELINESTATS], err = populateStatistics(data); err != nil {
+	if biasMap[BASELINESTATS], err = populateStatistics(data, options); err != nil {
 		return nil, err
 	}
 	return biasMap, nil
 }
 
-// matchFeature looks for geometries that match the given feature
-// If a match is found, a composite feature is created and the geometry is removed from the input collection
-// If no match is found, the feature is copied and the new copy gets updated properties
-func matchFeature(baselineFeature *geojson.Feature, detectedGeometries **geos.Geometry) (*geojson.Feature, error) {
+// matchCost is the cost of pairing a baseline line with a detected line:
+// the Hausdorff distance between them, or +Inf if they cannot be a match
+func matchCost(baselineGeometry, detectedGeometry *geos.Geometry, matchTolerance float64) (float64, error) {
 	var (
-		err error
-		baselineGeometry,
-		detectedGeometry *geos.Geometry
-		disjoint       bool
-		count          int
-		baselineClosed bool
-		detectedClosed bool
-		result         *geojson.Feature
+		err      error
+		distance float64
 	)
-	// Go from GeoJSON to GEOS
-	if baselineGeometry, err = toGeos(baselineFeature); err != nil {
-		return result, err
+	// To be a match they must come within the match tolerance of each other
+	if distance, err = baselineGeometry.Distance(detectedGeometry); err != nil {
+		return 0, err
 	}
-	// And from GEOS to a GEOS LineString
-	if baselineGeometry, err = lineStringFromGeometry(baselineGeometry); err != nil {
-		return result, err
+	if distance > matchTolerance {
+		return math.Inf(1), nil
 	}
-	if baselineClosed, err = baselineGeometry.IsClosed(); err != nil {
-		return result, err
+	return baselineGeometry.HausdorffDistance(detectedGeometry)
+}
+
+// matchCandidates returns the detected lines each baseline line can be paired with and the cost of each pairing.
+// Lines whose closure does not match under the closure rule cannot be paired,
+// and only detected lines whose bounding boxes come within the match tolerance are considered.
+func matchCandidates(baselineLines, detectedLines []*geos.Geometry, envelope *geos.Geometry, options qualitativeOptions) ([][]candidate, error) {
+	var (
+		err              error
+		baselineClosures []closure
+		detectedClosures []closure
+		box              bounds
+		detectedBounds   = make([]bounds, len(detectedLines))
+		cost             float64
+		result           = make([][]candidate, len(baselineLines))
+	)
+	if baselineClosures, err = lineClosures(baselineLines, envelope, options.closureRule, options.edgeTolerance); err != nil {
+		return nil, err
 	}
-	if count, err = (*detectedGeometries).NGeometry(); err != nil {
-		return result, err
+	if detectedClosures, err = lineClosures(detectedLines, envelope, options.closureRule, options.edgeTolerance); err != nil {
+		return nil, err
 	}
-	for inx := 0; inx < count; inx++ {
-		if detectedGeometry, err = (*detectedGeometries).Geometry(inx); err != nil {
-			return result, err
+	for jnx, detectedLine := range detectedLines {
+		if detectedBounds[jnx], err = lineBounds(detectedLine); err != nil {
+			return nil, err
+		}
+	}
+	index := newSTRtree(detectedBounds)
+	for inx, baselineLine := range baselineLines {
+		if box, err = lineBounds(baselineLine); err != nil {
+			return nil, err
+		}
+		for _, jnx := range index.query(box.expand(options.matchTolerance)) {
+			if !closuresMatch(baselineClosures[inx], detectedClosures[jnx], options.closureRule) {
+				continue
+			}
+			if cost, err = matchCost(baselineLine, detectedLines[jnx], options.matchTolerance); err != nil {
+				return nil, err
+			}
+			if !math.IsInf(cost, 1) {
+				result[inx] = append(result[inx], candidate{index: jnx, cost: cost})
+			}
 		}
+	}
+	return result, nil
+}
 
-		// To be a match they must both have the same closedness...
-		if detectedClosed, err = detectedGeometry.IsClosed(); err != nil {
-			return result, err
+// matchGroup is a set of baseline lines matched with a set of detected lines.
+// One side always has a single line; the other may have several fragments.
+type matchGroup struct {
+	baseline []int
+	detected []int
+}
+
+// groupMatches builds match groups from the optimal one-to-one assignment.
+// Each detected line left over is added as a fragment to the group of the closest baseline line
+// it can match, then each baseline line left over is added to the group of the closest
+// detected line it can match, provided that line is not already fragmented.
+// The result holds the group of each baseline line and of each detected line, or nil.
+func groupMatches(candidates [][]candidate, matches []int, detectedCount int) ([]*matchGroup, []*matchGroup) {
+	var (
+		baselineGroups = make([]*matchGroup, len(candidates))
+		detectedGroups = make([]*matchGroup, detectedCount)
+		// The baseline lines each detected line can be paired with
+		transposed = make([][]candidate, detectedCount)
+	)
+	for inx, jnx := range matches {
+		if jnx >= 0 {
+			baselineGroups[inx] = &matchGroup{baseline: []int{inx}, detected: []int{jnx}}
+			detectedGroups[jnx] = baselineGroups[inx]
+		}
+		for _, current := range candidates[inx] {
+			transposed[current.index] = append(transposed[current.index], candidate{index: inx, cost: current.cost})
+		}
+	}
+	// One baseline line, many detected fragments
+	for jnx := 0; jnx < detectedCount; jnx++ {
+		if detectedGroups[jnx] != nil {
+			continue
 		}
-		if baselineClosed != detectedClosed {
+		best := -1
+		bestCost := math.Inf(1)
+		for _, current := range transposed[jnx] {
+			if baselineGroups[current.index] != nil && current.cost < bestCost {
+				best, bestCost = current.index, current.cost
+			}
+		}
+		if best >= 0 {
+			baselineGroups[best].detected = append(baselineGroups[best].detected, jnx)
+			detectedGroups[jnx] = baselineGroups[best]
+		}
+	}
+	// Many baseline fragments, one detected line
+	for inx := range candidates {
+		if baselineGroups[inx] != nil {
 			continue
 		}
+		best := -1
+		bestCost := math.Inf(1)
+		for _, current := range candidates[inx] {
+			if group := detectedGroups[current.index]; group != nil && len(group.detected) == 1 && current.cost < bestCost {
+				best, bestCost = current.index, current.cost
+			}
+		}
+		if best >= 0 {
+			detectedGroups[best].baseline = append(detectedGroups[best].baseline, inx)
+			baselineGroups[inx] = detectedGroups[best]
+		}
+	}
+	return baselineGroups, detectedGroups
+}
+
+// fragmentation describes the fragments of one side of a match group
+func fragmentation(scene string, count int, gaps []float64) map[string]interface{} {
+	var total float64
+	for _, gap := range gaps {
+		total += gap
+	}
+	return map[string]interface{}{
+		SCENE:       scene,
+		"count":     count,
+		"gaps":      gaps,
+		"total_gap": total,
+		UNITS:       METERS}
+}
 
-		// And somehow overlap each other (not be disjoint)...
-		if disjoint, err = baselineGeometry.Disjoint(detectedGeometry); err != nil {
+// mergeGroup combines the lines of a match group, merging the fragments of whichever side has several.
+// It returns the GeoJSON and GEOS geometries of the baseline and detected sides
+// and a description of the fragments, or nil if there is only one line on each side.
+func mergeGroup(group *matchGroup, features []*geojson.Feature, baselineLines, detectedLines []*geos.Geometry) (interface{}, *geos.Geometry, *geos.Geometry, map[string]interface{}, error) {
+	var (
+		baselineGeojson interface{}
+		baseline        = baselineLines[group.baseline[0]]
+		detected        = detectedLines[group.detected[0]]
+		fragments       []*geos.Geometry
+		gaps            []float64
+		err             error
+	)
+	switch {
+	case len(group.detected) > 1:
+		for _, jnx := range group.detected {
+			fragments = append(fragments, detectedLines[jnx])
+		}
+		if detected, gaps, err = mergeFragments(baseline, fragments); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		return features[group.baseline[0]].Geometry, baseline, detected, fragmentation("detected", len(fragments), gaps), nil
+	case len(group.baseline) > 1:
+		for _, inx := range group.baseline {
+			fragments = append(fragments, baselineLines[inx])
+		}
+		if baseline, gaps, err = mergeFragments(detected, fragments); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if baselineGeojson, err = fromGeos(baseline); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		return baselineGeojson, baseline, detected, fragmentation("baseline", len(fragments), gaps), nil
+	}
+	return features[group.baseline[0]].Geometry, baseline, detected, nil, nil
+}
+
+// matchFeature creates the output feature for a baseline geometry.
+// If a detected geometry was matched to it, a composite feature is created;
+// otherwise the geometry is used as is and the feature gets updated properties
+func matchFeature(baselineGeojson interface{}, baselineGeometry, detectedGeometry, land *geos.Geometry, buffers shorelineBuffers, options qualitativeOptions) (*geojson.Feature, error) {
+	var (
+		err    error
+		result *geojson.Feature
+	)
+	if detectedGeometry == nil {
+		var undetected = make(map[string]interface{})
+		undetected[DETECTION] = "Undetected"
+		if err = addAgreement(undetected, baselineGeometry, nil, buffers); err != nil {
 			return result, err
 		}
+		result = geojson.NewFeature(baselineGeojson, "", undetected)
+		return result, err
+	}
 
-		if !disjoint {
-			// Now that we have a match
-			// Add some metadata regarding the match
-			var (
-				detectedGeojson interface{}
-				detected        = make(map[string]interface{})
-				data            stats.Float64Data
-			)
-			detected[DETECTION] = "Detected"
-			if data, err = lineStringsToFloat64Data(detectedGeometry, baselineGeometry); err != nil {
-				return result, err
-			}
-			if detected[DETECTEDSTATS], err = populateStatistics(data); err != nil {
-				return result, err
-			}
-			if data, err = lineStringsToFloat64Data(baselineGeometry, detectedGeometry); err != nil {
-				return result, err
+	// Add some metadata regarding the match
+	var (
+		detectedGeojson interface{}
+		detected        = make(map[string]interface{})
+		detectedData    stats.Float64Data
+		baselineData    stats.Float64Data
+	)
+	detected[DETECTION] = "Detected"
+	if detected[MATCHDISTANCE], err = baselineGeometry.Distance(detectedGeometry); err != nil {
+		return result, err
+	}
+	if detectedData, err = lineStringsToFloat64Data(detectedGeometry, baselineGeometry, options.sampleSpacing); err != nil {
+		return result, err
+	}
+	if detected[DETECTEDSTATS], err = populateStatistics(detectedData, options); err != nil {
+		return result, err
+	}
+	if baselineData, err = lineStringsToFloat64Data(baselineGeometry, detectedGeometry, options.sampleSpacing); err != nil {
+		return result, err
+	}
+	if detected[BASELINESTATS], err = populateStatistics(baselineData, options); err != nil {
+		return result, err
+	}
+	if detected[HAUSDORFF], err = hausdorffDistances(baselineGeometry, detectedGeometry); err != nil {
+		return result, err
+	}
+	if detected[FRECHET], err = frechetDistances(baselineGeometry, detectedGeometry, options.sampleSpacing); err != nil {
+		return result, err
+	}
+
+	if detected[DETECTIONBIAS], err = measureDisplacement(baselineGeometry, detectedGeometry, options); err != nil {
+		return result, err
+	}
+	var registration *Registration
+	if registration, err = registerLines(baselineGeometry, detectedGeometry, math.Inf(1), options); err != nil {
+		return result, err
+	}
+	if registration != nil {
+		detected[REGISTRATION] = registration
+	}
+	var offsets map[string]interface{}
+	if offsets, err = crossShoreOffsets(baselineGeometry, detectedGeometry, land, options.maxOffset, options); err != nil {
+		return result, err
+	}
+	if offsets != nil {
+		detected[OFFSET] = offsets
+	}
+	if err = addAgreement(detected, baselineGeometry, detectedGeometry, buffers); err != nil {
+		return result, err
+	}
+
+	// Create a new geometry as a GeometryCollection [baseline, detected]
+	if detectedGeojson, err = fromGeos(detectedGeometry); err != nil {
+		return result, err
+	}
+	slice := [...]interface{}{baselineGeojson, detectedGeojson}
+	result = geojson.NewFeature(geojson.NewGeometryCollection(slice[:]), "", detected)
+	return result, err
+}
+
+// segmentLines returns the non-empty lines of the result of an overlay
+func segmentLines(input *geos.Geometry) ([]*geos.Geometry, error) {
+	var (
+		result       []*geos.Geometry
+		lines        []*geos.Geometry
+		geometryType geos.GeometryType
+		empty        bool
+		err          error
+	)
+	if empty, err = input.IsEmpty(); err != nil || empty {
+		return nil, err
+	}
+	if input, err = input.LineMerge(); err != nil {
+		return nil, err
+	}
+	if geometryType, err = input.Type(); err != nil {
+		return nil, err
+	}
+	if geometryType == geos.LINESTRING {
+		return []*geos.Geometry{input}, nil
+	}
+	if lines, err = components(input); err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if geometryType, err = line.Type(); err != nil {
+			return nil, err
+		}
+		if empty, err = line.IsEmpty(); err != nil {
+			return nil, err
+		}
+		if geometryType == geos.LINESTRING && !empty {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
+// segmentFeatures splits a matched baseline line into the stretches within distance of the detected line
+// and the stretches outside it, returning a feature for each along with the total length of each kind.
+// Only the baseline within the envelope (of the detected scene) is split; the envelope may be nil.
+func segmentFeatures(baseline, detected, envelope *geos.Geometry, distance float64) ([]*geojson.Feature, float64, float64, error) {
+	var (
+		result         []*geojson.Feature
+		buffer         *geos.Geometry
+		segments       [2]*geos.Geometry
+		lengths        [2]float64
+		lines          []*geos.Geometry
+		length         float64
+		gjGeometry     interface{}
+		err            error
+		detectionNames = [2]string{"Detected Segment", "Undetected Segment"}
+	)
+	if baseline, err = clipToEnvelope(baseline, envelope); err != nil {
+		return nil, 0, 0, err
+	}
+	if buffer, err = detected.Buffer(distance); err != nil {
+		return nil, 0, 0, err
+	}
+	if segments[0], err = baseline.Intersection(buffer); err != nil {
+		return nil, 0, 0, err
+	}
+	if segments[1], err = baseline.Difference(buffer); err != nil {
+		return nil, 0, 0, err
+	}
+	for inx, segment := range segments {
+		if lines, err = segmentLines(segment); err != nil {
+			return nil, 0, 0, err
+		}
+		for _, line := range lines {
+			if length, err = line.Length(); err != nil {
+				return nil, 0, 0, err
 			}
-			if detected[BASELINESTATS], err = populateStatistics(data); err != nil {
-				return result, err
+			if gjGeometry, err = fromGeos(line); err != nil {
+				return nil, 0, 0, err
 			}
+			lengths[inx] += length
+			properties := map[string]interface{}{DETECTION: detectionNames[inx], LENGTH: length, UNITS: METERS}
+			result = append(result, geojson.NewFeature(gjGeometry, "", properties))
+		}
+	}
+	return result, lengths[0], lengths[1], nil
+}
 
-			if detected[DETECTIONBIAS], err = measureDisplacement(baselineGeometry, detectedGeometry); err != nil {
-				return result, err
-			}
+// sceneMatches are the baseline features and detected lines of two scenes and how they were matched
+type sceneMatches struct {
+	features       []*geojson.Feature
+	baselineLines  []*geos.Geometry
+	detectedLines  []*geos.Geometry
+	baselineGroups []*matchGroup
+	detectedGroups []*matchGroup
+}
 
-			// Create a new geometry as a GeometryCollection [baseline, detected]
-			if detectedGeojson, err = fromGeos(detectedGeometry); err != nil {
-				return result, err
-			}
-			slice := [...]interface{}{baselineFeature.Geometry, detectedGeojson}
-			result = geojson.NewFeature(geojson.NewGeometryCollection(slice[:]), "", detected)
+// matchScenes matches the baseline features with the detected lines
+func matchScenes(detected, baseline *Scene, options qualitativeOptions) (*sceneMatches, error) {
+	var (
+		geometry           *geos.Geometry
+		detectedGeometries *geos.Geometry
+		envelope           *geos.Geometry
+		candidates         [][]candidate
+		result             sceneMatches
+		err                error
+	)
+	if err = baseline.compatible(detected); err != nil {
+		return nil, err
+	}
+	if result.features, err = baseline.features(); err != nil {
+		return nil, err
+	}
 
-			// Since we have already found a match for this geometry
-			// we won't need to try to match it again later so remove it from the list
-			*detectedGeometries, err = (*detectedGeometries).Difference(detectedGeometry)
-			return result, err
+	// Go from GeoJSON to GEOS linework, a MultiLineString for features whose lines do not join
+	for _, feature := range result.features {
+		if geometry, err = toGeos(feature); err != nil {
+			return nil, err
 		}
+		if geometry, err = lineFromGeometry(geometry); err != nil {
+			return nil, err
+		}
+		result.baselineLines = append(result.baselineLines, geometry)
+	}
+	if detectedGeometries, err = detected.MultiLineString(); err != nil {
+		return nil, err
+	}
+	if result.detectedLines, err = components(detectedGeometries); err != nil {
+		return nil, err
 	}
 
-	// If we got here, there was no match
-	var undetected = make(map[string]interface{})
-	undetected[DETECTION] = "Undetected"
-	result = geojson.NewFeature(baselineFeature.Geometry, "", undetected)
-	return result, err
+	// Match the geometry for each feature with what we detected,
+	// then gather up the fragments the one-to-one assignment left over
+	if envelope, err = detected.envelope(); err != nil {
+		return nil, err
+	}
+	if candidates, err = matchCandidates(result.baselineLines, result.detectedLines, envelope, options); err != nil {
+		return nil, err
+	}
+	count := len(result.detectedLines)
+	result.baselineGroups, result.detectedGroups = groupMatches(candidates, assignCandidates(candidates, count), count)
+	return &result, nil
 }
-func qualitativeReview(detected Scene, baseline Scene) (*geojson.FeatureCollection, error) {
+
+// qualitativeReview matches baseline features with detected features and
+// measures how much of each scene is within tolerance of the other.
+// The land of the baseline tells which way is seaward; if it is nil, cross-shore offsets are measured to the left of the baseline.
+func qualitativeReview(detected Scene, baseline Scene, land *geos.Geometry, options qualitativeOptions) (*geojson.FeatureCollection, *QualitativeResult, error) {
 	var (
 		matchedFeatures    []*geojson.Feature
-		geometry           *geos.Geometry
 		err                error
-		count              int
-		features           []*geojson.Feature
 		detectedGeometries *geos.Geometry
+		baselineGeometries *geos.Geometry
+		envelope           *geos.Geometry
+		matches            *sceneMatches
+		registrations      []*Registration
 		matchedFeature     *geojson.Feature
+		buffers            shorelineBuffers
+		result             *QualitativeResult
 	)
 
-	if features, err = baseline.features(); err != nil {
-		return nil, err
+	if matches, err = matchScenes(&detected, &baseline, options); err != nil {
+		return nil, nil, err
 	}
 
 	if detectedGeometries, err = detected.MultiLineString(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if baselineGeometries, err = baseline.MultiLineString(); err != nil {
+		return nil, nil, err
+	}
+	if envelope, err = detected.envelope(); err != nil {
+		return nil, nil, err
+	}
+	if buffers, err = newShorelineBuffers(baselineGeometries, detectedGeometries, envelope, options.tolerance); err != nil {
+		return nil, nil, err
+	}
+	if result, err = sceneAgreement(baselineGeometries, detectedGeometries, buffers, options.tolerance); err != nil {
+		return nil, nil, err
 	}
 
-	// Try to match the geometry for each feature with what we detected
-	for _, feature := range features {
-		if matchedFeature, err = matchFeature(feature, &detectedGeometries); err != nil {
-			return nil, err
+	for inx, feature := range matches.features {
+		group := matches.baselineGroups[inx]
+		if group == nil {
+			if matchedFeature, err = matchFeature(feature.Geometry, matches.baselineLines[inx], nil, nil, buffers, options); err != nil {
+				return nil, nil, err
+			}
+			matchedFeatures = append(matchedFeatures, matchedFeature)
+			continue
+		}
+		// Groups with several baseline fragments are reported once
+		if group.baseline[0] != inx {
+			continue
+		}
+		var (
+			baselineGeojson    interface{}
+			baseline, detected *geos.Geometry
+			fragments          map[string]interface{}
+			segments           []*geojson.Feature
+		)
+		if baselineGeojson, baseline, detected, fragments, err = mergeGroup(group, matches.features, matches.baselineLines, matches.detectedLines); err != nil {
+			return nil, nil, err
+		}
+		if matchedFeature, err = matchFeature(baselineGeojson, baseline, detected, land, buffers, options); err != nil {
+			return nil, nil, err
+		}
+		if fragments != nil {
+			matchedFeature.Properties[FRAGMENTS] = fragments
+		}
+		if registration, ok := matchedFeature.Properties[REGISTRATION].(*Registration); ok {
+			registrations = append(registrations, registration)
+		}
+
+		// Show which stretches of the baseline were and were not detected
+		if segments, matchedFeature.Properties[DETECTEDLENGTH], matchedFeature.Properties[UNDETECTEDLENGTH], err = segmentFeatures(baseline, detected, buffers.envelope, options.segmentDistance()); err != nil {
+			return nil, nil, err
 		}
 
 		matchedFeatures = append(matchedFeatures, matchedFeature)
+		matchedFeatures = append(matchedFeatures, segments...)
 	}
+	result.Bias = sceneBias(registrations)
 
 	// Construct new features for the geometries that didn't match up
-	var newDetection = make(map[string]interface{})
-	newDetection[DETECTION] = "New Detection"
-	if count, err = detectedGeometries.NGeometry(); err != nil {
-		return nil, err
-	}
-	for inx := 0; inx < count; inx++ {
-		var gjGeometry interface{}
-		if geometry, err = detectedGeometries.Geometry(inx); err != nil {
-			return nil, err
+	for inx, line := range matches.detectedLines {
+		if matches.detectedGroups[inx] != nil {
+			continue
 		}
-		if gjGeometry, err = fromGeos(geometry); err != nil {
-			return nil, err
+		var (
+			gjGeometry   interface{}
+			newDetection = make(map[string]interface{})
+		)
+		if gjGeometry, err = fromGeos(line); err != nil {
+			return nil, nil, err
+		}
+		newDetection[DETECTION] = "New Detection"
+		if err = addAgreement(newDetection, nil, line, buffers); err != nil {
+			return nil, nil, err
 		}
 		matchedFeatures = append(matchedFeatures, geojson.NewFeature(gjGeometry, "", newDetection))
 	}
 
 	fc := geojson.NewFeatureCollection(matchedFeatures)
-	return fc, nil
+	return fc, result, nil
 }
 
-func populateStatistics(input stats.Float64Data) (map[string]interface{}, error) {
+// populateStatistics describes the distribution of distances
+func populateStatistics(input stats.Float64Data, options qualitativeOptions) (map[string]interface{}, error) {
 	var (
-		result = make(map[string]interface{})
-		err    error
+		result     = make(map[string]interface{})
+		min, max   float64
+		percentile float64
+		err        error
 	)
+	result[UNITS] = METERS
 	if result["mean"], err = input.Mean(); err != nil {
 		return result, err
 	}
-	result["median"], err = input.Median()
+	if result["median"], err = input.Median(); err != nil {
+		return result, err
+	}
+	if min, err = input.Min(); err != nil {
+		return result, err
+	}
+	if max, err = input.Max(); err != nil {
+		return result, err
+	}
+	result["min"] = min
+	result["max"] = max
+	if result["standard_deviation"], err = input.StandardDeviation(); err != nil {
+		return result, err
+	}
+	result["rmse"] = rmse(input)
+	for _, p := range options.percentiles {
+		if percentile, err = input.Percentile(p); err != nil {
+			return result, err
+		}
+		result["p"+strconv.FormatFloat(p, 'f', -1, 64)] = percentile
+	}
+	if options.histogramWidth > 0 {
+		if result["histogram"], err = histogram(input, min, max, options.histogramWidth); err != nil {
+			return result, err
+		}
+	}
 	return result, err
 }
+
+// rmse is the root mean square of the distances
+func rmse(input stats.Float64Data) float64 {
+	var sum float64
+	if len(input) == 0 {
+		return 0
+	}
+	for _, value := range input {
+		sum += value * value
+	}
+	return math.Sqrt(sum / float64(len(input)))
+}
+
+// histogram counts the values in bins of a fixed width,
+// aligned to multiples of the width so that histograms can be compared.
+// Widths that would need more than maxHistogramBins bins are rejected.
+func histogram(input stats.Float64Data, min, max, width float64) (map[string]interface{}, error) {
+	start := math.Floor(min/width) * width
+	bins := math.Floor((max-start)/width) + 1
+	if !(bins <= maxHistogramBins) {
+		return nil, fmt.Errorf("A histogram width of %v would need %v bins for distances from %v to %v; the most is %v", width, bins, min, max, maxHistogramBins)
+	}
+	counts := make([]int, int(bins))
+	for _, value := range input {
+		counts[int(math.Floor((value-start)/width))]++
+	}
+	return map[string]interface{}{"start": start, "width": width, "counts": counts}, nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/montanaflynn/stats"
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// featureScene returns a scene with a feature for each geometry
+func featureScene(geometries ...interface{}) Scene {
+	var features []*geojson.Feature
+	for _, geometry := range geometries {
+		features = append(features, geojson.NewFeature(geometry, "", map[string]interface{}{}))
+	}
+	return Scene{geoJSON: geojson.NewFeatureCollection(features)}
+}
+
+// TestGroupMatches checks that left over lines are gathered as fragments
+func TestGroupMatches(t *testing.T) {
+	// Baseline 0 was broken into detected 0 and 1
+	candidates := [][]candidate{{{0, 1}, {1, 2}}, {{2, 1}}}
+	baselineGroups, detectedGroups := groupMatches(candidates, assignCandidates(candidates, 3), 3)
+	if expected := (&matchGroup{baseline: []int{0}, detected: []int{0, 1}}); !reflect.DeepEqual(expected, baselineGroups[0]) {
+		t.Errorf("Expected %v, received %v", expected, baselineGroups[0])
+	}
+	if detectedGroups[1] != baselineGroups[0] || detectedGroups[2] != baselineGroups[1] {
+		t.Error("Detected lines are not in the groups of their baseline lines")
+	}
+
+	// Baseline 0 and 1 were detected as one line; baseline 2 was not detected
+	candidates = [][]candidate{{{0, 1}}, {{0, 2}}, nil}
+	baselineGroups, detectedGroups = groupMatches(candidates, assignCandidates(candidates, 1), 1)
+	if expected := (&matchGroup{baseline: []int{0, 1}, detected: []int{0}}); !reflect.DeepEqual(expected, detectedGroups[0]) {
+		t.Errorf("Expected %v, received %v", expected, detectedGroups[0])
+	}
+	if baselineGroups[1] != detectedGroups[0] || baselineGroups[2] != nil {
+		t.Error("Baseline lines are not in the expected groups")
+	}
+}
+
+// TestQualitativeReviewMultiLineString matches a baseline feature with two lines
+func TestQualitativeReviewMultiLineString(t *testing.T) {
+	var (
+		fc  *geojson.FeatureCollection
+		err error
+	)
+	baseline := featureScene(&geojson.MultiLineString{
+		Type:        geojson.MULTILINESTRING,
+		Coordinates: [][][]float64{{{0, 0}, {40, 0}}, {{60, 0}, {100, 0}}}})
+	detected := featureScene(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {100, 0}}})
+	options := qualitativeOptions{tolerance: 5, matchTolerance: 5, closureRule: STRICTCLOSURE, maxOffset: 10}
+	if fc, _, err = qualitativeReview(detected, baseline, nil, options); err != nil {
+		t.Fatalf("Failed to review a MultiLineString baseline: %v", err)
+	}
+	if len(fc.Features) == 0 {
+		t.Fatal("Expected features, received none")
+	}
+	if detection := fc.Features[0].Properties[DETECTION]; detection != "Detected" {
+		t.Errorf("Expected the baseline feature to be detected, received %v", detection)
+	}
+}
+
+// TestPopulateStatistics describes a small set of distances
+func TestPopulateStatistics(t *testing.T) {
+	options := qualitativeOptions{percentiles: []float64{50, 75}, histogramWidth: 2}
+	result, err := populateStatistics(stats.Float64Data{1, 2, 3, 4}, options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := map[string]float64{
+		"mean":               2.5,
+		"median":             2.5,
+		"min":                1,
+		"max":                4,
+		"standard_deviation": math.Sqrt(1.25),
+		"rmse":               math.Sqrt(7.5),
+		"p50":                2,
+		"p75":                3}
+	for key, value := range expected {
+		if actual, ok := result[key].(float64); !ok || math.Abs(actual-value) > 1e-12 {
+			t.Errorf("Expected %v to be %v, received %v", key, value, result[key])
+		}
+	}
+	expectedHistogram := map[string]interface{}{"start": 0.0, "width": 2.0, "counts": []int{1, 2, 1}}
+	if !reflect.DeepEqual(result["histogram"], expectedHistogram) {
+		t.Errorf("Expected histogram %v, received %v", expectedHistogram, result["histogram"])
+	}
+	if rmse(nil) != 0 {
+		t.Error("Expected the RMSE of no distances to be 0")
+	}
+}
+
+// TestHistogram aligns bins to the width and rejects widths that need too many bins
+func TestHistogram(t *testing.T) {
+	result, err := histogram(stats.Float64Data{3, 7.5, 10}, 3, 10, 5)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if counts := result["counts"].([]int); !reflect.DeepEqual(counts, []int{1, 1, 1}) || result["start"] != 0.0 {
+		t.Errorf("Expected counts [1 1 1] from 0, received %v from %v", counts, result["start"])
+	}
+	if _, err = histogram(stats.Float64Data{0, 100}, 0, 100, 1e-9); err == nil {
+		t.Error("Expected a histogram width of 1e-9 to be rejected")
+	}
+}
+
+// TestMatchTolerance matches parallel lines that come within the match tolerance without intersecting
+func TestMatchTolerance(t *testing.T) {
+	var (
+		baseline, detected, envelope *geos.Geometry
+		candidates                   [][]candidate
+		err                          error
+	)
+	if baseline, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if detected, err = geos.NewLineString(geos.NewCoord(0, 3), geos.NewCoord(100, 3)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if envelope, err = detected.Envelope(); err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, test := range []struct {
+		matchTolerance float64
+		matches        int
+	}{{0, 0}, {2, 0}, {5, 1}} {
+		options := qualitativeOptions{matchTolerance: test.matchTolerance, closureRule: STRICTCLOSURE}
+		if candidates, err = matchCandidates([]*geos.Geometry{baseline}, []*geos.Geometry{detected}, envelope, options); err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(candidates[0]) != test.matches {
+			t.Errorf("Expected %v candidates within %v, received %v", test.matches, test.matchTolerance, candidates[0])
+		}
+	}
+	if checkMatchTolerance(-1) == nil {
+		t.Error("Expected a negative match tolerance to be rejected")
+	}
+}
+
+// TestSegmentFeatures splits a baseline whose first half was detected
+func TestSegmentFeatures(t *testing.T) {
+	var (
+		baseline, detected *geos.Geometry
+		features           []*geojson.Feature
+		detectedLength     float64
+		undetectedLength   float64
+		err                error
+	)
+	if baseline, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if detected, err = geos.NewLineString(geos.NewCoord(0, 2), geos.NewCoord(50, 2)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if features, detectedLength, undetectedLength, err = segmentFeatures(baseline, detected, nil, 5); err != nil {
+		t.Fatal(err.Error())
+	}
+	// The buffer reaches about sqrt(5*5 - 2*2) past the end of the detected line
+	if detectedLength < 54 || detectedLength > 55 {
+		t.Errorf("Expected about 54.6 detected, received %v", detectedLength)
+	}
+	if math.Abs(detectedLength+undetectedLength-100) > 1e-9 {
+		t.Errorf("Expected the segments to add up to 100, received %v and %v", detectedLength, undetectedLength)
+	}
+	if len(features) != 2 {
+		t.Fatalf("Expected 2 segments, received %v", len(features))
+	}
+	for inx, expected := range []struct {
+		detection string
+		length    float64
+	}{{"Detected Segment", detectedLength}, {"Undetected Segment", undetectedLength}} {
+		properties := features[inx].Properties
+		if properties[DETECTION] != expected.detection || properties[LENGTH] != expected.length || properties[UNITS] != METERS {
+			t.Errorf("Expected a %v of %v, received %v", expected.detection, expected.length, properties)
+		}
+		if _, ok := features[inx].Geometry.(*geojson.LineString); !ok {
+			t.Errorf("Expected the %v to be a LineString, received %T", expected.detection, features[inx].Geometry)
+		}
+	}
+
+	// The baseline beyond the detected scene is not undetected
+	if _, detectedLength, undetectedLength, err = segmentFeatures(baseline, detected, rectangle(t, -10, -10, 60, 10), 5); err != nil {
+		t.Fatal(err.Error())
+	}
+	if detectedLength < 54 || detectedLength > 55 || math.Abs(detectedLength+undetectedLength-60) > 1e-9 {
+		t.Errorf("Expected the segments within the scene to add up to 60, received %v and %v", detectedLength, undetectedLength)
+	}
+}
@@ -17,105 +17,237 @@ limitations under the License.
 package main
 
 import (
-	"log"
+	"fmt"
 
 	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+const (
+	// QUANTITATIVE is the key for the FeatureCollection property containing
+	// the quantitative review of each scene
+	QUANTITATIVE = "quantitative"
+	// POSITIVE is the polarity of land polygons
+	POSITIVE = "positive"
+	// NEGATIVE is the polarity of water polygons
+	NEGATIVE = "negative"
+	// SCENE is the key for the GeoJSON property naming the scene a polygon came from
+	SCENE = "scene"
+	// POLARITY is the key for the GeoJSON property containing the polarity of a polygon
+	POLARITY = "polarity"
+	// TOTALAREA is the key for the GeoJSON property containing the area of a polygon, excluding holes
+	TOTALAREA = "total_area"
+	// BOUNDARYAREA is the key for the GeoJSON property containing the area within the shell of a polygon
+	BOUNDARYAREA = "boundary_area"
+	// DEPTH is the key for the GeoJSON property containing the number of steps
+	// from a polygon to the terminal polygon
+	DEPTH = "depth"
 )
 
 type polygonMetadata struct {
 	boundaryArea, totalArea float64
-	terminal                bool
-	link                    *polygonMetadata
-	index                   int
 }
 
-func quantitativeReview(scene Scene, envelope *geos.Geometry) error {
+// PolygonResult is the polarity and area of one polygon in the quantitative review
+type PolygonResult struct {
+	Index        int     `json:"index"`
+	Polarity     string  `json:"polarity"`
+	TotalArea    float64 `json:"total_area"`
+	BoundaryArea float64 `json:"boundary_area"`
+	Depth        int     `json:"depth"`
+	geometry     *geos.Geometry
+}
+
+// QuantitativeResult is the land/water area of a scene
+type QuantitativeResult struct {
+	PositiveArea float64         `json:"positive_area"`
+	NegativeArea float64         `json:"negative_area"`
+	NetArea      float64         `json:"net_area"`
+	TotalArea    float64         `json:"total_area"`
+	PolygonCount int             `json:"polygon_count"`
+	Polygons     []PolygonResult `json:"polygons"`
+	Units        string          `json:"units"`
+}
+
+// quantitativeReview measures the positive and negative space in a scene
+func quantitativeReview(scene Scene, envelope *geos.Geometry, polygonizer Polygonizer, anchor Anchor) (*QuantitativeResult, error) {
 	var (
-		err          error
-		polygon      *geos.Geometry
-		polygon2     *geos.Geometry
-		mpolygon     *geos.Geometry
-		boundary     *geos.Geometry
-		geometries   *geos.Geometry
-		count        int
-		touches      bool
-		positiveArea float64
-		negativeArea float64
+		err        error
+		polygon    *geos.Geometry
+		mpolygon   *geos.Geometry
+		boundary   *geos.Geometry
+		geometries *geos.Geometry
+		polygons   []*geos.Geometry
+		depths     []int
+		count      int
+		terminal   int
+		polarity   string
+		result     = QuantitativeResult{Units: SQUAREMETERS}
 	)
 
 	if geometries, err = scene.MultiLineString(); err != nil {
-		return err
+		return nil, err
 	}
-	if mpolygon, err = mlsToMPoly(geometries); err != nil {
-		return err
+	if mpolygon, err = mlsToMPoly(geometries, envelope, polygonizer); err != nil {
+		return nil, err
 	}
-	if count, err = mpolygon.NGeometry(); err != nil {
-		return err
+	if polygons, err = components(mpolygon); err != nil {
+		return nil, err
 	}
+	count = len(polygons)
 	var polygonMetadatas = make([]polygonMetadata, count)
 
-	for inx := 0; inx < count; inx++ {
-		polygonMetadatas[inx].index = inx
-		polygon, err = mpolygon.Geometry(inx)
-		if err != nil {
-			return err
-		}
+	for inx := range polygons {
+		polygon = polygons[inx]
 		// We need two areas for each component polygon
 		// The total area (which considers holes)
 		if polygonMetadatas[inx].totalArea, err = polygon.Area(); err != nil {
-			return err
+			return nil, err
 		}
 		// The shell (boundary)
 		if boundary, err = polygon.Shell(); err != nil {
-			return err
+			return nil, err
 		}
 		if boundary, err = geos.PolygonFromGeom(boundary); err != nil {
-			return err
+			return nil, err
 		}
 		if polygonMetadatas[inx].boundaryArea, err = boundary.Area(); err != nil {
-			return err
+			return nil, err
 		}
+	}
+	if count == 0 {
+		return &result, nil
+	}
 
-		// Construct an ordered acyclical graph of spaces,
-		// with the first polygon being the terminal node
-		if inx == 0 {
-			polygonMetadatas[inx].terminal = true
+	// The anchor tells us which polygon is the terminal node and what it is
+	if terminal, polarity, err = anchor.Terminal(polygons, envelope); err != nil {
+		return nil, err
+	}
+	if depths, err = polygonDepths(polygons, terminal); err != nil {
+		return nil, err
+	}
+	for inx := 0; inx < count; inx++ {
+		polygonResult := PolygonResult{
+			Index:        inx,
+			TotalArea:    polygonMetadatas[inx].totalArea,
+			BoundaryArea: polygonMetadatas[inx].boundaryArea,
+			Depth:        depths[inx],
+			geometry:     polygons[inx]}
+		polygonResult.Polarity = polarity
+		if depths[inx]%2 == 1 {
+			polygonResult.Polarity = oppositePolarity(polarity)
 		}
-		// Iterate through all of the polygons
-		for jnx := 1; jnx < count; jnx++ {
+		switch polygonResult.Polarity {
+		case POSITIVE:
+			result.PositiveArea += polygonMetadatas[inx].totalArea
+			result.NegativeArea += polygonMetadatas[inx].boundaryArea - polygonMetadatas[inx].totalArea
+		case NEGATIVE:
+			result.NegativeArea += polygonMetadatas[inx].totalArea
+			result.PositiveArea += polygonMetadatas[inx].boundaryArea - polygonMetadatas[inx].totalArea
+		}
+		result.Polygons = append(result.Polygons, polygonResult)
+	}
+	result.NetArea = result.PositiveArea - result.NegativeArea
+	result.TotalArea = result.PositiveArea + result.NegativeArea
+	result.PolygonCount = count
+	return &result, err
+}
+
+// polygonDepths counts the steps from each polygon to the terminal polygon
+// through an acyclical graph of spaces, linking each polygon
+// to a touching polygon one step closer to the terminal polygon
+func polygonDepths(polygons []*geos.Geometry, terminal int) ([]int, error) {
+	var (
+		linked  = make([]bool, len(polygons))
+		depths  = make([]int, len(polygons))
+		touches bool
+		err     error
+	)
+	linked[terminal] = true
+	queue := []int{terminal}
+	for len(queue) > 0 {
+		inx := queue[0]
+		queue = queue[1:]
+		for jnx := range polygons {
 			// If a polygon is not already linked
-			if (inx == jnx) || (polygonMetadatas[jnx].link != nil) {
+			if linked[jnx] {
 				continue
 			}
-			if polygon2, err = mpolygon.Geometry(jnx); err != nil {
-				return err
-			}
-			if touches, err = polygon2.Touches(polygon); err != nil {
-				return err
+			if touches, err = polygons[jnx].Touches(polygons[inx]); err != nil {
+				return nil, err
 			}
 			// And it touches the current polygon, register the link
 			if touches {
-				polygonMetadatas[jnx].link = &(polygonMetadatas[inx])
+				linked[jnx] = true
+				depths[jnx] = depths[inx] + 1
+				queue = append(queue, jnx)
 			}
 		}
 	}
-	for inx := 0; inx < count; inx++ {
-		counter := 0
-		// Count the steps to get from the current polygon to the terminal one
-		// to determine its polarity
-		for current := inx; !polygonMetadatas[current].terminal; {
-			current = polygonMetadatas[current].link.index
-			counter++
+	for inx := range polygons {
+		if !linked[inx] {
+			return nil, fmt.Errorf("Polygon %v does not touch any other polygon so its polarity is unknown", inx)
+		}
+	}
+	return depths, nil
+}
+
+func oppositePolarity(polarity string) string {
+	if polarity == POSITIVE {
+		return NEGATIVE
+	}
+	return POSITIVE
+}
+
+// polygonFeatures returns a GeoJSON Feature for each polygon in the result
+// so analysts can see which areas were considered positive and negative
+func polygonFeatures(scene string, result *QuantitativeResult) ([]*geojson.Feature, error) {
+	var (
+		features []*geojson.Feature
+		err      error
+	)
+	for _, polygon := range result.Polygons {
+		var (
+			gjGeometry interface{}
+			properties = make(map[string]interface{})
+		)
+		if gjGeometry, err = fromGeos(polygon.geometry); err != nil {
+			return nil, err
 		}
-		switch counter % 2 {
-		case 0:
-			positiveArea += polygonMetadatas[inx].totalArea
-			negativeArea += polygonMetadatas[inx].boundaryArea - polygonMetadatas[inx].totalArea
-		case 1:
-			negativeArea += polygonMetadatas[inx].totalArea
-			positiveArea += polygonMetadatas[inx].boundaryArea - polygonMetadatas[inx].totalArea
+		properties[SCENE] = scene
+		properties[POLARITY] = polygon.Polarity
+		properties[TOTALAREA] = polygon.TotalArea
+		properties[BOUNDARYAREA] = polygon.BoundaryArea
+		properties[DEPTH] = polygon.Depth
+		properties[UNITS] = result.Units
+		features = append(features, geojson.NewFeature(gjGeometry, "", properties))
+	}
+	return features, nil
+}
+
+// polygonCollection returns the polygons of the baseline and detected reviews as a FeatureCollection,
+// returned from the working CRS to longitude and latitude. A nil review is left out.
+func polygonCollection(baseline, detected *QuantitativeResult, working CRS) (*geojson.FeatureCollection, error) {
+	var (
+		features []*geojson.Feature
+		output   interface{}
+		err      error
+	)
+	for _, scene := range []struct {
+		name   string
+		result *QuantitativeResult
+	}{{"baseline", baseline}, {"detected", detected}} {
+		var sceneFeatures []*geojson.Feature
+		if scene.result == nil {
+			continue
 		}
+		if sceneFeatures, err = polygonFeatures(scene.name, scene.result); err != nil {
+			return nil, err
+		}
+		features = append(features, sceneFeatures...)
+	}
+	if output, err = transformGeoJSON(geojson.NewFeatureCollection(features), reprojectFunc(working, geographic{})); err != nil {
+		return nil, err
 	}
-	log.Printf("+:%v -:%v Sum: %v Total:%v\n", positiveArea, negativeArea, positiveArea-negativeArea, positiveArea+negativeArea)
-	return err
+	return output.(*geojson.FeatureCollection), nil
 }
@@ -0,0 +1,112 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// splitScene is a 100 x 100 scene split by a shoreline at y = 30
+// with a 20 x 20 island north of it
+func splitScene(t *testing.T) (Scene, *geos.Geometry) {
+	scene := featureScene(
+		&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 30}, {100, 30}}},
+		&geojson.LineString{Type: geojson.LINESTRING, Coordinates: square(40, 50, 20)})
+	envelope, err := toGeos(&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{square(0, 0, 100)}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return scene, envelope
+}
+
+// TestQuantitativeReview measures the land and water of a scene whose water is the largest polygon on its edge
+func TestQuantitativeReview(t *testing.T) {
+	var (
+		result *QuantitativeResult
+		err    error
+	)
+	scene, envelope := splitScene(t)
+	if result, err = quantitativeReview(scene, envelope, geosPolygonizer{}, edgeAnchor{}); err != nil {
+		t.Fatalf("Quantitative review failed: %v", err)
+	}
+	if result.PolygonCount != 2 || len(result.Polygons) != 2 {
+		t.Fatalf("Expected 2 polygons, received %v", result.PolygonCount)
+	}
+	// The land south of the shoreline plus the island
+	if result.PositiveArea != 3400 {
+		t.Errorf("Expected a positive area of 3400, received %v", result.PositiveArea)
+	}
+	if result.NegativeArea != 6600 {
+		t.Errorf("Expected a negative area of 6600, received %v", result.NegativeArea)
+	}
+	if result.NetArea != -3200 || result.TotalArea != 10000 {
+		t.Errorf("Expected net and total areas of -3200 and 10000, received %v and %v", result.NetArea, result.TotalArea)
+	}
+	for _, polygon := range result.Polygons {
+		switch polygon.TotalArea {
+		case 3000:
+			if polygon.Polarity != POSITIVE || polygon.Depth != 1 || polygon.BoundaryArea != 3000 {
+				t.Errorf("Expected the land to be positive at depth 1, received %v", polygon)
+			}
+		case 6600:
+			if polygon.Polarity != NEGATIVE || polygon.Depth != 0 || polygon.BoundaryArea != 7000 {
+				t.Errorf("Expected the water to be negative at depth 0 with a boundary area of 7000, received %v", polygon)
+			}
+		default:
+			t.Errorf("Unexpected polygon %v", polygon)
+		}
+	}
+}
+
+// TestPolygonCollection describes each polygon and returns it to longitude and latitude
+func TestPolygonCollection(t *testing.T) {
+	var (
+		polygon *geos.Geometry
+		fc      *geojson.FeatureCollection
+		err     error
+	)
+	projection := newUTM(-77, 40)
+	x, y := projection.FromGeographic(-77, 40)
+	if polygon, err = toGeos(&geojson.Polygon{Type: geojson.POLYGON, Coordinates: [][][]float64{square(x, y, 100)}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	baseline := &QuantitativeResult{Units: SQUAREMETERS, Polygons: []PolygonResult{
+		{Polarity: POSITIVE, TotalArea: 10000, BoundaryArea: 10000, Depth: 1, geometry: polygon}}}
+	// The detected review failed
+	if fc, err = polygonCollection(baseline, nil, projection); err != nil {
+		t.Fatalf("Failed to create polygons: %v", err)
+	}
+	if len(fc.Features) != 1 {
+		t.Fatalf("Expected 1 polygon, received %v", len(fc.Features))
+	}
+	properties := fc.Features[0].Properties
+	expected := map[string]interface{}{
+		SCENE: "baseline", POLARITY: POSITIVE, TOTALAREA: 10000.0, BOUNDARYAREA: 10000.0, DEPTH: 1, UNITS: SQUAREMETERS}
+	for key, value := range expected {
+		if properties[key] != value {
+			t.Errorf("Expected %v to be %v, received %v", key, value, properties[key])
+		}
+	}
+	coordinates := fc.Features[0].Geometry.(*geojson.Polygon).Coordinates
+	if lon, lat := coordinates[0][0][0], coordinates[0][0][1]; math.Abs(lon+77) > 1e-6 || math.Abs(lat-40) > 1e-6 {
+		t.Errorf("Expected the polygon to start at -77,40, received %v,%v", lon, lat)
+	}
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/montanaflynn/stats"
+	"github.com/paulsmith/gogeos/geos"
+)
+
+const (
+	// REGISTRATION is the key for the property containing the rigid registration
+	// of detected linework onto baseline linework
+	REGISTRATION = "registration"
+	// icpIterations is the most iterations of iterative closest point registration
+	icpIterations = 50
+	// icpRotation and icpTranslation are the rotation (radians) and translation (meters)
+	// below which an iteration is considered to have converged
+	icpRotation    = 1e-9
+	icpTranslation = 1e-6
+)
+
+// rigidTransform is a rotation followed by a translation
+type rigidTransform struct {
+	cos, sin, tx, ty float64
+}
+
+var identityTransform = rigidTransform{cos: 1}
+
+// apply transforms a coordinate; it is a transformFunc
+func (r rigidTransform) apply(x, y float64) (float64, float64) {
+	return r.cos*x - r.sin*y + r.tx, r.sin*x + r.cos*y + r.ty
+}
+
+// then returns the transform that applies r and then next
+func (r rigidTransform) then(next rigidTransform) rigidTransform {
+	tx, ty := next.apply(r.tx, r.ty)
+	return rigidTransform{
+		cos: next.cos*r.cos - next.sin*r.sin,
+		sin: next.sin*r.cos + next.cos*r.sin,
+		tx:  tx,
+		ty:  ty}
+}
+
+// Registration is the rigid transform that best moves detected linework onto baseline linework
+type Registration struct {
+	// Rotation is counterclockwise, in degrees, about the centroid of the detected points
+	Rotation float64 `json:"rotation"`
+	// Easting and Northing are the displacement of the centroid of the detected points
+	Easting  float64 `json:"easting"`
+	Northing float64 `json:"northing"`
+	// Iterations is the number of ICP iterations performed
+	Iterations int `json:"iterations"`
+	// Points is the number of detected points with a corresponding baseline point after registration
+	Points int `json:"points"`
+	// Residuals are the statistics of the distances between corresponding points after registration
+	Residuals map[string]interface{} `json:"residuals,omitempty"`
+	Units     string                 `json:"units"`
+	transform rigidTransform
+}
+
+// segment is a segment of a line; first and last mark the open ends of the line
+type segment struct {
+	start, end  geos.Coord
+	first, last bool
+}
+
+// segmentIndex finds the closest point on a set of lines
+type segmentIndex struct {
+	segments []segment
+	tree     strtree
+}
+
+func newSegmentIndex(lines [][]geos.Coord) segmentIndex {
+	var (
+		box   bounds
+		boxes []bounds
+		index segmentIndex
+	)
+	for _, line := range lines {
+		if len(line) < 2 {
+			continue
+		}
+		open := line[0].X != line[len(line)-1].X || line[0].Y != line[len(line)-1].Y
+		for inx := 1; inx < len(line); inx++ {
+			start, end := line[inx-1], line[inx]
+			index.segments = append(index.segments, segment{
+				start: start,
+				end:   end,
+				first: open && inx == 1,
+				last:  open && inx == len(line)-1})
+			box = bounds{minX: start.X, minY: start.Y, maxX: start.X, maxY: start.Y}
+			boxes = append(boxes, box.extend(bounds{minX: end.X, minY: end.Y, maxX: end.X, maxY: end.Y}))
+		}
+	}
+	index.tree = newSTRtree(boxes)
+	return index
+}
+
+// closest returns the closest point on the lines within maxDistance of the point and its distance.
+// It returns false if there is none or if the closest point is an open end of a line,
+// since points beyond the ends of a line have no real counterpart on it.
+func (s segmentIndex) closest(point geos.Coord, maxDistance float64) (geos.Coord, float64, bool) {
+	var (
+		result   geos.Coord
+		best     = math.Inf(1)
+		atEnd    bool
+		found    bool
+		position = bounds{minX: point.X, minY: point.Y, maxX: point.X, maxY: point.Y}
+	)
+	for _, inx := range s.tree.query(position.expand(maxDistance)) {
+		current := s.segments[inx]
+		dx, dy := current.end.X-current.start.X, current.end.Y-current.start.Y
+		fraction := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			fraction = math.Max(0, math.Min(1, ((point.X-current.start.X)*dx+(point.Y-current.start.Y)*dy)/length))
+		}
+		candidate := geos.NewCoord(current.start.X+fraction*dx, current.start.Y+fraction*dy)
+		if distance := math.Hypot(point.X-candidate.X, point.Y-candidate.Y); distance < best {
+			result, best, found = candidate, distance, true
+			atEnd = (fraction == 0 && current.first) || (fraction == 1 && current.last)
+		}
+	}
+	if !found || best > maxDistance || atEnd {
+		return result, best, false
+	}
+	return result, best, true
+}
+
+// correspondences pairs each transformed source point with the closest point on the target
+func correspondences(source []geos.Coord, target segmentIndex, transform rigidTransform, maxDistance float64) ([]geos.Coord, []geos.Coord, stats.Float64Data) {
+	var (
+		from, to  []geos.Coord
+		residuals stats.Float64Data
+	)
+	for _, point := range source {
+		moved := geos.NewCoord(transform.apply(point.X, point.Y))
+		if closest, distance, ok := target.closest(moved, maxDistance); ok {
+			from = append(from, moved)
+			to = append(to, closest)
+			residuals = append(residuals, distance)
+		}
+	}
+	return from, to, residuals
+}
+
+// procrustes returns the rigid transform that best moves the from points onto the to points
+// in the least squares sense
+func procrustes(from, to []geos.Coord) rigidTransform {
+	var dot, cross float64
+	fromX, fromY := coordCentroid(from)
+	toX, toY := coordCentroid(to)
+	for inx := range from {
+		ax, ay := from[inx].X-fromX, from[inx].Y-fromY
+		bx, by := to[inx].X-toX, to[inx].Y-toY
+		dot += ax*bx + ay*by
+		cross += ax*by - ay*bx
+	}
+	angle := math.Atan2(cross, dot)
+	result := rigidTransform{cos: math.Cos(angle), sin: math.Sin(angle)}
+	x, y := result.apply(fromX, fromY)
+	result.tx, result.ty = toX-x, toY-y
+	return result
+}
+
+// registerPoints registers source points onto target lines with iterative closest point (ICP).
+// Points farther than maxDistance from the target, or closest to one of its open ends, are ignored.
+// It returns nil if there are too few corresponding points to register.
+func registerPoints(source []geos.Coord, target segmentIndex, maxDistance float64, options qualitativeOptions) (*Registration, error) {
+	var (
+		result    = Registration{Units: METERS, transform: identityTransform}
+		from, to  []geos.Coord
+		residuals stats.Float64Data
+		err       error
+	)
+	for result.Iterations < icpIterations {
+		if from, to, _ = correspondences(source, target, result.transform, maxDistance); len(from) < 2 {
+			break
+		}
+		step := procrustes(from, to)
+		result.transform = result.transform.then(step)
+		result.Iterations++
+		// Measure the step by how far it moves the corresponding points
+		x, y := coordCentroid(from)
+		movedX, movedY := step.apply(x, y)
+		if math.Abs(math.Atan2(step.sin, step.cos)) < icpRotation && math.Hypot(movedX-x, movedY-y) < icpTranslation {
+			break
+		}
+	}
+	if result.Iterations == 0 {
+		return nil, nil
+	}
+
+	// Describe the transform by how it moves the centroid of the source points
+	centerX, centerY := coordCentroid(source)
+	x, y := result.transform.apply(centerX, centerY)
+	result.Easting, result.Northing = x-centerX, y-centerY
+	result.Rotation = math.Atan2(result.transform.sin, result.transform.cos) * 180 / math.Pi
+
+	_, _, residuals = correspondences(source, target, result.transform, maxDistance)
+	result.Points = len(residuals)
+	if len(residuals) > 0 {
+		if result.Residuals, err = populateStatistics(residuals, options); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// coordCentroid is the mean of the coordinates
+func coordCentroid(coords []geos.Coord) (float64, float64) {
+	var x, y float64
+	for _, coord := range coords {
+		x += coord.X / float64(len(coords))
+		y += coord.Y / float64(len(coords))
+	}
+	return x, y
+}
+
+// registerLines registers detected linework onto baseline linework
+func registerLines(baseline, detected *geos.Geometry, maxDistance float64, options qualitativeOptions) (*Registration, error) {
+	var (
+		source []geos.Coord
+		target [][]geos.Coord
+		err    error
+	)
+	if source, err = lineCoords(detected, options.sampleSpacing); err != nil {
+		return nil, err
+	}
+	if target, err = lineCoordArrays(baseline); err != nil {
+		return nil, err
+	}
+	return registerPoints(source, newSegmentIndex(target), maxDistance, options)
+}
+
+// registerScene registers the whole detected scene onto the baseline scene and moves it into place.
+// Points farther than the tolerance from the baseline are ignored.
+func registerScene(detected, baseline *Scene, options qualitativeOptions) (*Registration, error) {
+	var (
+		detectedGeometries *geos.Geometry
+		baselineGeometries *geos.Geometry
+		result             *Registration
+		err                error
+	)
+	if err = baseline.compatible(detected); err != nil {
+		return nil, err
+	}
+	if detectedGeometries, err = detected.MultiLineString(); err != nil {
+		return nil, err
+	}
+	if baselineGeometries, err = baseline.MultiLineString(); err != nil {
+		return nil, err
+	}
+	if result, err = registerLines(baselineGeometries, detectedGeometries, options.tolerance, options); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("Too few detected points are within %v %v of the baseline to register the scene", options.tolerance, METERS)
+	}
+	return result, detected.transform(result.transform.apply)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulsmith/gogeos/geos"
+)
+
+// TestRegisterPoints recovers a known rotation and translation
+// between lines with different extents
+func TestRegisterPoints(t *testing.T) {
+	var (
+		target       []geos.Coord
+		source       []geos.Coord
+		registration *Registration
+		err          error
+	)
+	for x := 0.0; x <= 400; x += 2 {
+		target = append(target, geos.NewCoord(500000+x, 4000000+20*math.Sin(x/15)))
+	}
+	// Rotate by 1 degree about the middle of the line and shift
+	angle := math.Pi / 180
+	distortion := rigidTransform{cos: math.Cos(angle), sin: math.Sin(angle)}
+	x, y := distortion.apply(500200, 4000000)
+	distortion.tx, distortion.ty = 500200-x+3, 4000000-y-4
+	for _, coord := range target[20:180] {
+		source = append(source, geos.NewCoord(distortion.apply(coord.X, coord.Y)))
+	}
+
+	if registration, err = registerPoints(source, newSegmentIndex([][]geos.Coord{target}), 30, qualitativeOptions{}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if math.Abs(registration.Rotation+1) > 1e-6 {
+		t.Errorf("Expected a rotation of -1 degree, received %v", registration.Rotation)
+	}
+	for inx, coord := range source {
+		x, y := registration.transform.apply(coord.X, coord.Y)
+		if math.Hypot(x-target[inx+20].X, y-target[inx+20].Y) > 1e-4 {
+			t.Fatalf("Expected %v to register to %v, received %v, %v", coord, target[inx+20], x, y)
+		}
+	}
+	if registration.Residuals == nil || registration.Residuals["max"].(float64) > 1e-4 {
+		t.Errorf("Expected small residuals, received %v", registration.Residuals)
+	}
+}
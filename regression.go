@@ -0,0 +1,133 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import "math"
+
+// linearRegression fits y = a + slope*x by least squares.
+// It returns the slope, its standard error (NaN with fewer than 3 points)
+// and the coefficient of determination (NaN if y does not vary).
+func linearRegression(x, y []float64) (float64, float64, float64) {
+	var (
+		count                  = float64(len(x))
+		meanX, meanY           float64
+		sxx, sxy, syy, squares float64
+	)
+	for inx := range x {
+		meanX += x[inx] / count
+		meanY += y[inx] / count
+	}
+	for inx := range x {
+		dx, dy := x[inx]-meanX, y[inx]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+	for inx := range x {
+		residual := y[inx] - intercept - slope*x[inx]
+		squares += residual * residual
+	}
+	standardError, rSquared := math.NaN(), math.NaN()
+	if len(x) > 2 {
+		standardError = math.Sqrt(squares/(count-2)) / math.Sqrt(sxx)
+	}
+	if syy > 0 {
+		rSquared = 1 - squares/syy
+	}
+	return slope, standardError, rSquared
+}
+
+// studentTQuantile returns the value below which a Student's t random variable
+// with the given degrees of freedom falls with probability p (0.5 <= p < 1)
+func studentTQuantile(p, dof float64) float64 {
+	low, high := 0.0, 1.0
+	for studentTCDF(high, dof) < p {
+		low, high = high, high*2
+	}
+	for iteration := 0; iteration < 100; iteration++ {
+		middle := (low + high) / 2
+		if studentTCDF(middle, dof) < p {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return (low + high) / 2
+}
+
+// studentTCDF is the cumulative distribution function of Student's t distribution
+func studentTCDF(t, dof float64) float64 {
+	tail := incompleteBeta(dof/2, 0.5, dof/(dof+t*t)) / 2
+	if t < 0 {
+		return tail
+	}
+	return 1 - tail
+}
+
+// incompleteBeta is the regularized incomplete beta function I_x(a, b)
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly on this side of the mean
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, 1-x)/b
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta function
+// with the modified Lentz method
+func betaFraction(a, b, x float64) float64 {
+	const (
+		tiny      = 1e-300
+		precision = 1e-15
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+	for m := 1.0; m <= 300; m++ {
+		for _, numerator := range [2]float64{
+			m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m)),
+			-(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))} {
+			if d = 1 + numerator*d; math.Abs(d) < tiny {
+				d = tiny
+			}
+			if c = 1 + numerator/c; math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			result *= d * c
+		}
+		if math.Abs(d*c-1) < precision {
+			break
+		}
+	}
+	return result
+}
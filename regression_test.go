@@ -0,0 +1,63 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLinearRegression fits a line through points with known residuals
+func TestLinearRegression(t *testing.T) {
+	x := []float64{0, 1, 2, 3}
+	y := []float64{1, 3, 5, 7}
+	if slope, standardError, rSquared := linearRegression(x, y); slope != 2 || standardError != 0 || rSquared != 1 {
+		t.Errorf("Expected a perfect fit with a slope of 2, received %v, %v, %v", slope, standardError, rSquared)
+	}
+	y = []float64{1, 4, 5, 8}
+	// The fit is y = 1.2 + 2.2x with residuals -0.2, 0.6, -0.6, 0.2
+	slope, standardError, rSquared := linearRegression(x, y)
+	if math.Abs(slope-2.2) > 1e-12 {
+		t.Errorf("Expected a slope of 2.2, received %v", slope)
+	}
+	if expected := math.Sqrt(0.8/2) / math.Sqrt(5); math.Abs(standardError-expected) > 1e-12 {
+		t.Errorf("Expected a standard error of %v, received %v", expected, standardError)
+	}
+	if expected := 1 - 0.8/25; math.Abs(rSquared-expected) > 1e-12 {
+		t.Errorf("Expected R² of %v, received %v", expected, rSquared)
+	}
+	if _, standardError, _ = linearRegression(x[:2], y[:2]); !math.IsNaN(standardError) {
+		t.Errorf("Expected no standard error from 2 points, received %v", standardError)
+	}
+}
+
+// TestStudentTQuantile compares quantiles with published tables
+func TestStudentTQuantile(t *testing.T) {
+	cases := []struct{ p, dof, expected float64 }{
+		{0.975, 1, 12.7062},
+		{0.975, 2, 4.3027},
+		{0.975, 10, 2.2281},
+		{0.975, 30, 2.0423},
+		{0.95, 5, 2.0150},
+		{0.995, 20, 2.8453},
+	}
+	for _, c := range cases {
+		if result := studentTQuantile(c.p, c.dof); math.Abs(result-c.expected) > 1e-4 {
+			t.Errorf("Expected %v for p=%v with %v degrees of freedom, received %v", c.expected, c.p, c.dof, result)
+		}
+	}
+}
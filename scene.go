@@ -26,19 +26,40 @@ import (
 // Scene is a shoreline scene, consisting of linework for shoreline features
 type Scene struct {
 	geoJSON         interface{}
+	crs             CRS
 	multiLineString *geos.Geometry
 }
 
+// readScene reads a scene from a GeoJSON file.
+// The CRS is crsName if given, otherwise the crs member of the file, otherwise CRS84.
+func readScene(filename, crsName string) (Scene, error) {
+	var (
+		result Scene
+		err    error
+	)
+	if result.geoJSON, crsName, err = readGeoJSON(filename, crsName); err != nil {
+		return result, err
+	}
+	if crsName == "" {
+		crsName = CRS84
+	}
+	if result.crs, err = parseCRS(crsName); err != nil {
+		return result, fmt.Errorf("Could not read the CRS of %v: %v", filename, err)
+	}
+	return result, nil
+}
+
 // MultiLineString creates a geos.MultiLineString from the input and joins
 // individual LineStrings together
-func (s Scene) MultiLineString() (*geos.Geometry, error) {
+func (s *Scene) MultiLineString() (*geos.Geometry, error) {
 	if s.multiLineString != nil {
 		return s.multiLineString, nil
 	}
 	var (
-		geometry *geos.Geometry
-		result   *geos.Geometry
-		err      error
+		geometry   *geos.Geometry
+		geometries []*geos.Geometry
+		result     *geos.Geometry
+		err        error
 	)
 
 	result, _ = geos.NewCollection(geos.MULTILINESTRING)
@@ -50,16 +71,15 @@ func (s Scene) MultiLineString() (*geos.Geometry, error) {
 		if geometry, err = toGeos(current); err != nil {
 			return nil, err
 		}
-		// If we get a polygon, we really just want its outer ring here
-		ttype, _ := geometry.Type()
-		if ttype == geos.POLYGON {
-			if geometry, err = geometry.Shell(); err != nil {
+		// If we get polygons, we really just want their outer rings here
+		if geometries, err = shorelines(geometry); err != nil {
+			return nil, err
+		}
+		for _, geometry = range geometries {
+			if result, err = result.Union(geometry); err != nil {
 				return nil, err
 			}
 		}
-		if result, err = result.Union(geometry); err != nil {
-			return nil, err
-		}
 	}
 	// Join the geometries when possible
 	if result, err = result.LineMerge(); err != nil {
@@ -69,6 +89,86 @@ func (s Scene) MultiLineString() (*geos.Geometry, error) {
 	return s.multiLineString, err
 }
 
+// shorelines reduces a geometry to the linework we compare:
+// polygons contribute their outer rings and collections are searched recursively
+func shorelines(input *geos.Geometry) ([]*geos.Geometry, error) {
+	var (
+		result   []*geos.Geometry
+		members  []*geos.Geometry
+		geometry *geos.Geometry
+		gType    geos.GeometryType
+		err      error
+	)
+	if gType, err = input.Type(); err != nil {
+		return nil, err
+	}
+	switch gType {
+	case geos.POLYGON:
+		if geometry, err = input.Shell(); err != nil {
+			return nil, err
+		}
+		result = append(result, geometry)
+	case geos.MULTIPOLYGON, geos.GEOMETRYCOLLECTION:
+		if members, err = components(input); err != nil {
+			return nil, err
+		}
+		for _, geometry = range members {
+			var lines []*geos.Geometry
+			if lines, err = shorelines(geometry); err != nil {
+				return nil, err
+			}
+			result = append(result, lines...)
+		}
+	default:
+		result = append(result, input)
+	}
+	return result, nil
+}
+
+// coordinateSystem returns the CRS of the scene, which defaults to CRS84
+func (s *Scene) coordinateSystem() CRS {
+	if s.crs == nil {
+		return geographic{}
+	}
+	return s.crs
+}
+
+// reproject transforms the scene into the target CRS
+func (s *Scene) reproject(target CRS) error {
+	if sameCRS(s.coordinateSystem(), target) {
+		return nil
+	}
+	if err := s.transform(reprojectFunc(s.coordinateSystem(), target)); err != nil {
+		return err
+	}
+	s.crs = target
+	return nil
+}
+
+// transform moves every coordinate of the scene without changing its CRS
+func (s *Scene) transform(transform transformFunc) error {
+	var (
+		geoJSON interface{}
+		err     error
+	)
+	if geoJSON, err = transformGeoJSON(s.geoJSON, transform); err != nil {
+		return err
+	}
+	s.geoJSON = geoJSON
+	s.multiLineString = nil
+	return nil
+}
+
+// compatible returns an error if the two scenes cannot be compared
+// because they are in different CRSs
+func (s *Scene) compatible(other *Scene) error {
+	if !sameCRS(s.coordinateSystem(), other.coordinateSystem()) {
+		return fmt.Errorf("Cannot compare a scene in %v with a scene in %v without transforming them",
+			s.coordinateSystem(), other.coordinateSystem())
+	}
+	return nil
+}
+
 // Features returns the GeoJSON Features
 func (s Scene) features() ([]*geojson.Feature, error) {
 
@@ -80,7 +180,7 @@ func (s Scene) features() ([]*geojson.Feature, error) {
 	}
 }
 
-func (s Scene) envelope() (*geos.Geometry, error) {
+func (s *Scene) envelope() (*geos.Geometry, error) {
 	result, err := s.MultiLineString()
 	if err != nil {
 		return nil, err
@@ -88,8 +188,12 @@ func (s Scene) envelope() (*geos.Geometry, error) {
 	return result.Envelope()
 }
 
-func (s Scene) clip(input Scene) error {
+// clip restricts the linework of the scene to the envelope of the input
+func (s *Scene) clip(input *Scene) error {
 	var geometry *geos.Geometry
+	if err := s.compatible(input); err != nil {
+		return err
+	}
 	envelope, err := input.envelope()
 	if err != nil {
 		return err
@@ -102,6 +206,11 @@ func (s Scene) clip(input Scene) error {
 	if err != nil {
 		return err
 	}
+	// Intersection can leave behind points where lines graze the envelope
+	// so keep only the (merged) linework
+	if geometry, err = geometry.LineMerge(); err != nil {
+		return err
+	}
 	s.multiLineString = geometry
 	return err
 }
@@ -17,7 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"io/ioutil"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/paulsmith/gogeos/geos"
@@ -44,7 +48,7 @@ func TestScene(t *testing.T) {
 		t.Errorf("Failed to produced the detected scene envelope: %v", err.Error())
 	}
 	log.Printf("Envelope: %v\n", envelope.String())
-	if err = baselineScene.clip(detectedScene); err != nil {
+	if err = baselineScene.clip(&detectedScene); err != nil {
 		t.Error(err.Error())
 	}
 	if envelope, err = baselineScene.envelope(); err != nil {
@@ -61,3 +65,108 @@ func TestDisplace(t *testing.T) {
 	geom, _ = displace(geom, 2, 3)
 	log.Printf("Geom: %v", geom.String())
 }
+
+// TestClip restricts the baseline to the envelope of the detected scene
+func TestClip(t *testing.T) {
+	var (
+		geometry *geos.Geometry
+		gType    geos.GeometryType
+		length   float64
+		err      error
+	)
+	baseline := featureScene(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{-50, 5}, {150, 5}}})
+	detected := featureScene(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {100, 10}}})
+	if err = baseline.clip(&detected); err != nil {
+		t.Fatal(err.Error())
+	}
+	if geometry, err = baseline.MultiLineString(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if gType, _ = geometry.Type(); gType != geos.LINESTRING {
+		t.Errorf("Expected the clipped baseline to be merged into a LineString, received %v", gType)
+	}
+	if length, _ = geometry.Length(); length != 100 {
+		t.Errorf("Expected the clipped baseline to be 100 long, received %v", length)
+	}
+}
+
+// TestReproject projects a scene into UTM and back again
+func TestReproject(t *testing.T) {
+	var (
+		geometry *geos.Geometry
+		length   float64
+		err      error
+	)
+	scene := featureScene(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{-77, 40}, {-76.99, 40}}})
+	if _, err = scene.MultiLineString(); err != nil {
+		t.Fatal(err.Error())
+	}
+	projection := newUTM(-77, 40)
+	if err = scene.reproject(projection); err != nil {
+		t.Fatal(err.Error())
+	}
+	if scene.coordinateSystem().String() != projection.String() {
+		t.Errorf("Expected the scene to be in %v, received %v", projection, scene.coordinateSystem())
+	}
+	// The linework is rebuilt in meters
+	if geometry, err = scene.MultiLineString(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if length, _ = geometry.Length(); math.Abs(length-854) > 2 {
+		t.Errorf("Expected the projected line to be about 854 meters long, received %v", length)
+	}
+	if err = scene.reproject(geographic{}); err != nil {
+		t.Fatal(err.Error())
+	}
+	features, _ := scene.features()
+	for inx, coord := range features[0].Geometry.(*geojson.LineString).Coordinates {
+		if lon := []float64{-77, -76.99}[inx]; math.Abs(coord[0]-lon) > 1e-9 || math.Abs(coord[1]-40) > 1e-9 {
+			t.Errorf("Expected %v,40 to round trip, received %v", lon, coord)
+		}
+	}
+}
+
+// TestCompatible refuses to compare scenes in different CRSs
+func TestCompatible(t *testing.T) {
+	geographicScene := featureScene(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{-77, 40}, {-76.99, 40}}})
+	projectedScene := featureScene(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{329274, 4429673}, {330127, 4429673}}})
+	projectedScene.crs = newUTM(-77, 40)
+	if err := geographicScene.compatible(&projectedScene); err == nil {
+		t.Error("Expected scenes in CRS84 and UTM to be incompatible")
+	}
+	if err := geographicScene.clip(&projectedScene); err == nil {
+		t.Error("Expected clipping by a scene in another CRS to fail")
+	}
+	other := featureScene(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{-77, 40.01}, {-76.99, 40.01}}})
+	if err := geographicScene.compatible(&other); err != nil {
+		t.Errorf("Expected scenes in CRS84 to be compatible, received %v", err)
+	}
+}
+
+// TestReadSceneCRS lets the CRS given override a crs member that cannot be read
+func TestReadSceneCRS(t *testing.T) {
+	var (
+		dir   string
+		scene Scene
+		err   error
+	)
+	if dir, err = ioutil.TempDir("", "scene"); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "linked.geojson")
+	document := `{"type":"FeatureCollection","crs":{"type":"link","properties":{"href":"crs.wkt"}},"features":[` +
+		`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[329274,4429673],[330127,4429673]]},"properties":{}}]}`
+	if err = ioutil.WriteFile(filename, []byte(document), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err = readScene(filename, ""); err == nil {
+		t.Error("Expected a linked crs to be unreadable")
+	}
+	if scene, err = readScene(filename, "EPSG:32618"); err != nil {
+		t.Fatalf("Expected the CRS given to override the crs member, received %v", err)
+	}
+	if name := scene.coordinateSystem().String(); name != "EPSG:32618" {
+		t.Errorf("Expected EPSG:32618, received %v", name)
+	}
+}
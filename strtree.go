@@ -0,0 +1,150 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/paulsmith/gogeos/geos"
+)
+
+// strNodeCapacity is the number of children of each node of an STRtree
+const strNodeCapacity = 10
+
+// bounds is an axis-aligned bounding box
+type bounds struct {
+	minX, minY, maxX, maxY float64
+}
+
+// lineBounds returns the bounding box of a line
+func lineBounds(line *geos.Geometry) (bounds, error) {
+	var (
+		result = bounds{minX: math.Inf(1), minY: math.Inf(1), maxX: math.Inf(-1), maxY: math.Inf(-1)}
+		coords []geos.Coord
+		err    error
+	)
+	if coords, err = lineCoords(line, 0); err != nil {
+		return result, err
+	}
+	for _, coord := range coords {
+		result = result.extend(bounds{minX: coord.X, minY: coord.Y, maxX: coord.X, maxY: coord.Y})
+	}
+	return result, nil
+}
+
+// extend returns the bounding box of both boxes
+func (b bounds) extend(other bounds) bounds {
+	return bounds{
+		minX: math.Min(b.minX, other.minX),
+		minY: math.Min(b.minY, other.minY),
+		maxX: math.Max(b.maxX, other.maxX),
+		maxY: math.Max(b.maxY, other.maxY)}
+}
+
+// expand returns the box grown by distance on every side
+func (b bounds) expand(distance float64) bounds {
+	return bounds{minX: b.minX - distance, minY: b.minY - distance, maxX: b.maxX + distance, maxY: b.maxY + distance}
+}
+
+// intersects returns true if the boxes share any point
+func (b bounds) intersects(other bounds) bool {
+	return b.minX <= other.maxX && other.minX <= b.maxX && b.minY <= other.maxY && other.minY <= b.maxY
+}
+
+type strNode struct {
+	bounds   bounds
+	children []*strNode
+	item     int
+}
+
+// strtree is a static R-tree bulk loaded with the Sort-Tile-Recursive algorithm
+type strtree struct {
+	root *strNode
+}
+
+// newSTRtree indexes the items by their bounding boxes; items are identified by their index
+func newSTRtree(items []bounds) strtree {
+	var level []*strNode
+	for inx, item := range items {
+		level = append(level, &strNode{bounds: item, item: inx})
+	}
+	if len(level) == 0 {
+		return strtree{}
+	}
+	for len(level) > 1 {
+		level = packSTR(level)
+	}
+	return strtree{root: level[0]}
+}
+
+// packSTR groups the nodes of one level into the parent level:
+// the nodes are sorted into vertical slices by x, and each slice into runs by y
+func packSTR(level []*strNode) []*strNode {
+	var (
+		result   []*strNode
+		parents  = (len(level) + strNodeCapacity - 1) / strNodeCapacity
+		slices   = int(math.Ceil(math.Sqrt(float64(parents))))
+		perSlice = slices * strNodeCapacity
+	)
+	centerX := func(node *strNode) float64 { return node.bounds.minX + node.bounds.maxX }
+	centerY := func(node *strNode) float64 { return node.bounds.minY + node.bounds.maxY }
+	sort.SliceStable(level, func(inx, jnx int) bool { return centerX(level[inx]) < centerX(level[jnx]) })
+	for start := 0; start < len(level); start += perSlice {
+		slice := level[start:minInt(start+perSlice, len(level))]
+		sort.SliceStable(slice, func(inx, jnx int) bool { return centerY(slice[inx]) < centerY(slice[jnx]) })
+		for first := 0; first < len(slice); first += strNodeCapacity {
+			parent := &strNode{children: slice[first:minInt(first+strNodeCapacity, len(slice))]}
+			parent.bounds = parent.children[0].bounds
+			for _, child := range parent.children[1:] {
+				parent.bounds = parent.bounds.extend(child.bounds)
+			}
+			result = append(result, parent)
+		}
+	}
+	return result
+}
+
+// query returns the items whose bounding boxes intersect the box, in increasing order
+func (t strtree) query(box bounds) []int {
+	var result []int
+	if t.root == nil {
+		return result
+	}
+	stack := []*strNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !node.bounds.intersects(box) {
+			continue
+		}
+		if node.children == nil {
+			result = append(result, node.item)
+			continue
+		}
+		stack = append(stack, node.children...)
+	}
+	sort.Ints(result)
+	return result
+}
+
+func minInt(first, second int) int {
+	if first < second {
+		return first
+	}
+	return second
+}
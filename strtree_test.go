@@ -0,0 +1,54 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// TestSTRtree compares queries against a linear scan
+func TestSTRtree(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	box := func(size float64) bounds {
+		x, y := random.Float64()*1000, random.Float64()*1000
+		return bounds{minX: x, minY: y, maxX: x + random.Float64()*size, maxY: y + random.Float64()*size}
+	}
+	items := make([]bounds, 500)
+	for inx := range items {
+		items[inx] = box(20)
+	}
+	tree := newSTRtree(items)
+	for query := 0; query < 100; query++ {
+		var (
+			search   = box(100)
+			expected []int
+		)
+		for inx, item := range items {
+			if item.intersects(search) {
+				expected = append(expected, inx)
+			}
+		}
+		if result := tree.query(search); !reflect.DeepEqual(expected, result) {
+			t.Errorf("Expected %v for %v, received %v", expected, search, result)
+		}
+	}
+	if result := newSTRtree(nil).query(box(100)); len(result) != 0 {
+		t.Errorf("Expected nothing from an empty tree, received %v", result)
+	}
+}
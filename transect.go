@@ -0,0 +1,387 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+const (
+	// TRANSECTMODE measures shoreline change along transects cast from the baseline
+	TRANSECTMODE = "transect"
+	// TRANSECT is the key for the GeoJSON property containing the number of a transect
+	TRANSECT = "transect"
+	// STATION is the key for the GeoJSON property containing the distance along the baseline of a transect
+	STATION = "station"
+	// DISTANCES is the key for the GeoJSON property containing the distance along a transect
+	// to each shoreline that crosses it, positive seaward
+	DISTANCES = "distances"
+	// NSM is the key for the GeoJSON property containing the net shoreline movement:
+	// the distance from the oldest dated shoreline to the youngest along a transect
+	NSM = "nsm"
+	// EPR is the key for the GeoJSON property containing the end point rate:
+	// the net shoreline movement divided by the time between the oldest and youngest shorelines
+	EPR = "epr"
+	// RATEUNITS is the key for the GeoJSON property naming the units of rates
+	RATEUNITS = "rate_units"
+	// METERSPERYEAR are the units of rates
+	METERSPERYEAR = "m/yr"
+	// SHORELINES is the key for the GeoJSON property containing the number of shorelines crossing a transect
+	SHORELINES = "shorelines"
+	// SCE is the key for the GeoJSON property containing the shoreline change envelope:
+	// the distance between the shorelines farthest apart along a transect
+	SCE = "sce"
+	// ECI is the key for the GeoJSON property containing the confidence interval of the end point rate,
+	// from the uncertainty of the shoreline positions
+	ECI = "eci"
+	// LRR is the key for the GeoJSON property containing the linear regression rate:
+	// the slope of the least squares line through the dated shoreline positions along a transect
+	LRR = "lrr"
+	// LCI is the key for the GeoJSON property containing the half-width of the confidence interval
+	// of the linear regression rate
+	LCI = "lci"
+	// LR2 is the key for the GeoJSON property containing the coefficient of determination of the linear regression
+	LR2 = "lr2"
+	// CONFIDENCE is the key for the FeatureCollection property containing the confidence level of LCI, in percent
+	CONFIDENCE = "confidence"
+	// SCENES is the key for the FeatureCollection property listing the shorelines
+	SCENES = "scenes"
+	// BASELINEDATE is the key for the FeatureCollection property containing the date of the baseline
+	BASELINEDATE = "baseline_date"
+	// dateLayout is the layout of dates
+	dateLayout = "2006-01-02"
+	// yearLength is the mean length of a year
+	yearLength = 365.25 * 24 * time.Hour
+)
+
+// transectOptions controls how transects are cast
+type transectOptions struct {
+	// spacing is the distance in meters between transects along the baseline
+	spacing float64
+	// length is the distance in meters a transect extends to either side of the baseline
+	length float64
+	// confidence is the confidence level in percent of the linear regression rate's confidence interval
+	confidence float64
+	// uncertainty is the uncertainty in meters of each shoreline position, or 0 if it is unknown
+	uncertainty float64
+}
+
+// datePattern finds dates such as 2016-01-31 or 20160131 in file names.
+// It leaves the character after a date for the next match, so the date must be checked
+// not to run on into more digits.
+var datePattern = regexp.MustCompile(`(?:^|[^\d])(\d{4})-?(\d{2})-?(\d{2})`)
+
+// transect is a line perpendicular to the baseline, directed seaward if seaward is true
+// and to the left of the baseline otherwise
+type transect struct {
+	origin  geos.Coord
+	nx, ny  float64
+	station float64
+	seaward bool
+}
+
+// transectScene is a dated shoreline that transects are intersected with
+type transectScene struct {
+	Name  string `json:"name"`
+	Date  string `json:"date,omitempty"`
+	date  time.Time
+	lines segmentIndex
+}
+
+// newTransectScene indexes the linework of a scene for intersecting with transects.
+// The date may be empty if it is unknown.
+func newTransectScene(name, date string, scene *Scene) (transectScene, error) {
+	var (
+		result = transectScene{Name: name, Date: date}
+		lines  [][]geos.Coord
+		mls    *geos.Geometry
+		err    error
+	)
+	if date != "" {
+		if result.date, err = time.Parse(dateLayout, date); err != nil {
+			return result, err
+		}
+	}
+	if mls, err = scene.MultiLineString(); err != nil {
+		return result, err
+	}
+	if lines, err = lineCoordArrays(mls); err != nil {
+		return result, err
+	}
+	result.lines = newSegmentIndex(lines)
+	return result, nil
+}
+
+// parseDates parses a comma-separated list of dates such as "2016-01-31,2016-06-30"
+func parseDates(input string) ([]string, error) {
+	var result []string
+	if strings.TrimSpace(input) == "" {
+		return result, nil
+	}
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if _, err := time.Parse(dateLayout, part); err != nil {
+			return nil, err
+		}
+		result = append(result, part)
+	}
+	return result, nil
+}
+
+// castTransects casts transects perpendicular to each baseline line at a fixed spacing,
+// directed seaward if the land tells which side that is and to the left of the line otherwise
+func castTransects(baseline, land *geos.Geometry, spacing float64) ([]transect, error) {
+	var (
+		result  []transect
+		lines   [][]geos.Coord
+		sign    float64
+		station float64
+		err     error
+	)
+	if spacing <= 0 {
+		return nil, errors.New("Transect spacing must be positive")
+	}
+	if lines, err = lineCoordArrays(baseline); err != nil {
+		return nil, err
+	}
+	for _, coords := range lines {
+		sign = 0
+		if land != nil {
+			if sign, err = seawardSign(coords, land); err != nil {
+				return nil, err
+			}
+		}
+		seaward := sign != 0
+		if !seaward {
+			sign = 1
+		}
+		// The normal at a station is the normal of the segment it falls on
+		next := 0.0
+		for inx := 1; inx < len(coords); inx++ {
+			start, end := coords[inx-1], coords[inx]
+			length := math.Hypot(end.X-start.X, end.Y-start.Y)
+			if length == 0 {
+				continue
+			}
+			for ; next < length; next += spacing {
+				fraction := next / length
+				result = append(result, transect{
+					origin:  geos.NewCoord(start.X+fraction*(end.X-start.X), start.Y+fraction*(end.Y-start.Y)),
+					nx:      -sign * (end.Y - start.Y) / length,
+					ny:      sign * (end.X - start.X) / length,
+					station: station + next,
+					seaward: seaward})
+			}
+			next -= length
+			station += length
+		}
+	}
+	return result, nil
+}
+
+// orderScenes returns the scenes from oldest to youngest.
+// Undated scenes come first, in the order given, followed by the dated scenes by date.
+func orderScenes(scenes []transectScene) []transectScene {
+	result := make([]transectScene, len(scenes))
+	copy(result, scenes)
+	sort.SliceStable(result, func(inx, jnx int) bool { return result[inx].date.Before(result[jnx].date) })
+	return result
+}
+
+// shorelinePosition is where a shoreline crosses a transect
+type shorelinePosition struct {
+	// scene is the index of the shoreline among the ordered scenes
+	scene    int
+	distance float64
+}
+
+// transectStatistics describes the movement of the shorelines crossing a transect.
+// Net movement and the end point rate run from the oldest dated shoreline to the youngest.
+// If only one shoreline is dated, they run between it and the baseline, at a distance of 0, if the baseline is dated;
+// if neither, net movement runs from the first shoreline to the last.
+// The envelope needs three shorelines and the linear regression three dated shorelines.
+func transectStatistics(positions []shorelinePosition, scenes []transectScene, baselineDate time.Time, options transectOptions) map[string]interface{} {
+	var (
+		result                      = make(map[string]interface{})
+		years                       []float64
+		distances                   []float64
+		dated                       []shorelinePosition
+		lowest                      = math.Inf(1)
+		highest                     = math.Inf(-1)
+		firstDate, lastDate         time.Time
+		firstDistance, lastDistance float64
+		elapsed                     float64
+		rSquared                    float64
+		rate                        float64
+		rateError                   float64
+		rateMeasured                bool
+	)
+	result[SHORELINES] = len(positions)
+	for _, position := range positions {
+		lowest = math.Min(lowest, position.distance)
+		highest = math.Max(highest, position.distance)
+		if !scenes[position.scene].date.IsZero() {
+			dated = append(dated, position)
+		}
+	}
+	if len(positions) >= 3 {
+		result[SCE] = highest - lowest
+	}
+
+	switch {
+	case len(dated) >= 2:
+		first, last := dated[0], dated[len(dated)-1]
+		firstDate, firstDistance = scenes[first.scene].date, first.distance
+		lastDate, lastDistance = scenes[last.scene].date, last.distance
+	case len(dated) == 1 && !baselineDate.IsZero():
+		firstDate, lastDate = baselineDate, scenes[dated[0].scene].date
+		lastDistance = dated[0].distance
+		if lastDate.Before(firstDate) {
+			firstDate, lastDate = lastDate, firstDate
+			firstDistance, lastDistance = lastDistance, firstDistance
+		}
+	case len(positions) >= 2:
+		result[NSM] = positions[len(positions)-1].distance - positions[0].distance
+		return result
+	default:
+		return result
+	}
+	result[NSM] = lastDistance - firstDistance
+	if elapsed = float64(lastDate.Sub(firstDate)) / float64(yearLength); elapsed > 0 {
+		result[EPR] = (lastDistance - firstDistance) / elapsed
+		if options.uncertainty > 0 {
+			// Both shorelines are equally uncertain
+			result[ECI] = math.Sqrt2 * options.uncertainty / elapsed
+		}
+		rateMeasured = true
+	}
+	if len(dated) >= 3 {
+		for _, position := range dated {
+			years = append(years, float64(scenes[position.scene].date.Sub(scenes[dated[0].scene].date))/float64(yearLength))
+			distances = append(distances, position.distance)
+		}
+		if rate, rateError, rSquared = linearRegression(years, distances); !math.IsNaN(rate) {
+			result[LRR] = rate
+			if !math.IsNaN(rateError) {
+				result[LCI] = studentTQuantile(0.5+options.confidence/200, float64(len(dated)-2)) * rateError
+			}
+			if !math.IsNaN(rSquared) {
+				result[LR2] = rSquared
+			}
+			rateMeasured = true
+		}
+	}
+	if rateMeasured {
+		result[RATEUNITS] = METERSPERYEAR
+	}
+	return result
+}
+
+// transectReview casts transects from the baseline and measures the distance along each
+// to each shoreline, oldest first, returning a LineString feature for each transect.
+// The baseline is not a shoreline, but if its date is not zero, movement is measured from it
+// along transects that only one dated shoreline crosses.
+func transectReview(baseline *geos.Geometry, baselineDate time.Time, scenes []transectScene, land *geos.Geometry, options transectOptions) (*geojson.FeatureCollection, error) {
+	var (
+		transects []transect
+		features  []*geojson.Feature
+		err       error
+	)
+	if len(scenes) == 0 {
+		return nil, errors.New("Transects need at least one shoreline")
+	}
+	if transects, err = castTransects(baseline, land, options.spacing); err != nil {
+		return nil, err
+	}
+	scenes = orderScenes(scenes)
+	for inx, current := range transects {
+		var (
+			distances = make(map[string]float64)
+			positions []shorelinePosition
+		)
+		for jnx, scene := range scenes {
+			distance, ok := scene.lines.crossing(current.origin, current.nx, current.ny, options.length)
+			if !ok {
+				continue
+			}
+			distances[scene.Name] = distance
+			positions = append(positions, shorelinePosition{scene: jnx, distance: distance})
+		}
+		properties := transectStatistics(positions, scenes, baselineDate, options)
+		properties[TRANSECT] = inx
+		properties[STATION] = current.station
+		properties[DIRECTION] = LEFT
+		if current.seaward {
+			properties[DIRECTION] = SEAWARD
+		}
+		properties[DISTANCES] = distances
+		properties[UNITS] = METERS
+		coordinates := [][]float64{
+			{current.origin.X - options.length*current.nx, current.origin.Y - options.length*current.ny},
+			{current.origin.X + options.length*current.nx, current.origin.Y + options.length*current.ny}}
+		features = append(features, geojson.NewFeature(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: coordinates}, "", properties))
+	}
+	return geojson.NewFeatureCollection(features), nil
+}
+
+// sceneDate finds the date of a scene: the named property of its first feature that has one,
+// or else the first date (YYYY-MM-DD or YYYYMMDD) in its file name. It returns "" if there is none.
+func sceneDate(filename string, scene *Scene, property string) (string, error) {
+	var (
+		features []*geojson.Feature
+		date     time.Time
+		err      error
+	)
+	if property != "" {
+		if features, err = scene.features(); err != nil {
+			return "", err
+		}
+		for _, feature := range features {
+			value, ok := feature.Properties[property].(string)
+			if !ok {
+				continue
+			}
+			for _, layout := range []string{time.RFC3339, dateLayout, "20060102"} {
+				if date, err = time.Parse(layout, value); err == nil {
+					return date.Format(dateLayout), nil
+				}
+			}
+			return "", fmt.Errorf("Could not read the %v %v of %v", property, value, filename)
+		}
+	}
+	name := filepath.Base(filename)
+	for _, match := range datePattern.FindAllStringSubmatchIndex(name, -1) {
+		if match[1] < len(name) && unicode.IsDigit(rune(name[match[1]])) {
+			continue
+		}
+		if date, err = time.Parse(dateLayout, name[match[2]:match[3]]+"-"+name[match[4]:match[5]]+"-"+name[match[6]:match[7]]); err == nil {
+			return date.Format(dateLayout), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2016, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulsmith/gogeos/geos"
+	"github.com/venicegeo/geojson-go/geojson"
+)
+
+// TestTransectReview measures the movement of a shoreline over ten years
+func TestTransectReview(t *testing.T) {
+	var (
+		baseline *geos.Geometry
+		fc       *geojson.FeatureCollection
+		err      error
+	)
+	if baseline, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	// The baseline is only a reference between the shorelines
+	earlierCoords := []geos.Coord{geos.NewCoord(0, -5), geos.NewCoord(100, -5)}
+	laterCoords := []geos.Coord{geos.NewCoord(0, 5), geos.NewCoord(100, 5)}
+	scenes := []transectScene{
+		{Name: "later", date: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), lines: newSegmentIndex([][]geos.Coord{laterCoords})},
+		{Name: "earlier", date: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), lines: newSegmentIndex([][]geos.Coord{earlierCoords})}}
+	if fc, err = transectReview(baseline, time.Time{}, scenes, nil, transectOptions{spacing: 40, length: 50}); err != nil {
+		t.Fatal(err.Error())
+	}
+	features := fc.Features
+	if len(features) != 3 {
+		t.Fatalf("Expected 3 transects, received %v", len(features))
+	}
+	for inx, feature := range features {
+		if station := feature.Properties[STATION].(float64); station != float64(40*inx) {
+			t.Errorf("Expected transect %v at station %v, received %v", inx, 40*inx, station)
+		}
+		if direction := feature.Properties[DIRECTION]; direction != LEFT {
+			t.Errorf("Expected transect %v to point to the left without land, received %v", inx, direction)
+		}
+		expected := map[string]float64{"earlier": -5, "later": 5}
+		if distances := feature.Properties[DISTANCES].(map[string]float64); !reflect.DeepEqual(expected, distances) {
+			t.Errorf("Expected distances %v, received %v", expected, distances)
+		}
+		if nsm := feature.Properties[NSM].(float64); nsm != 10 {
+			t.Errorf("Expected a net shoreline movement of 10, received %v", nsm)
+		}
+		years := float64(scenes[0].date.Sub(scenes[1].date)) / float64(yearLength)
+		if epr := feature.Properties[EPR].(float64); math.Abs(epr-10/years) > 1e-9 {
+			t.Errorf("Expected an end point rate of %v, received %v", 10/years, epr)
+		}
+	}
+
+	// A single shoreline moves from the dated baseline
+	baselineDate := time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC)
+	if fc, err = transectReview(baseline, baselineDate, scenes[:1], nil, transectOptions{spacing: 40, length: 50}); err != nil {
+		t.Fatal(err.Error())
+	}
+	years := float64(scenes[0].date.Sub(baselineDate)) / float64(yearLength)
+	for _, feature := range fc.Features {
+		if nsm := feature.Properties[NSM].(float64); nsm != 5 {
+			t.Errorf("Expected a net shoreline movement of 5 from the baseline, received %v", nsm)
+		}
+		if epr := feature.Properties[EPR].(float64); math.Abs(epr-5/years) > 1e-9 {
+			t.Errorf("Expected an end point rate of %v from the baseline, received %v", 5/years, epr)
+		}
+	}
+	if _, err = transectReview(baseline, baselineDate, nil, nil, transectOptions{spacing: 40, length: 50}); err == nil {
+		t.Error("Expected an error without shorelines")
+	}
+}
+
+// TestTransectMainDuplicates rejects a detected file given twice, whose distances would collide
+func TestTransectMainDuplicates(t *testing.T) {
+	args := []string{"test/baseline.geojson", "transects.geojson", "test/detected.geojson", "test/detected.geojson"}
+	if err := transectMain(args, "", "", nil, "date", "", FIRSTANCHOR, "", "", nil, transectOptions{spacing: 50, length: 500}); err == nil {
+		t.Error("Expected an error for a detected file given twice")
+	}
+}
+
+// TestCastTransects points transects seaward, or to the left of the baseline without land
+func TestCastTransects(t *testing.T) {
+	var (
+		baseline  *geos.Geometry
+		transects []transect
+		err       error
+	)
+	if baseline, err = geos.NewLineString(geos.NewCoord(0, 0), geos.NewCoord(100, 0)); err != nil {
+		t.Fatal(err.Error())
+	}
+	tests := []struct {
+		name    string
+		land    *geos.Geometry
+		ny      float64
+		seaward bool
+	}{
+		{"land to the south", rectangle(t, -50, -50, 150, 0), 1, true},
+		{"land to the north", rectangle(t, -50, 0, 150, 50), -1, true},
+		{"land far away", rectangle(t, -50, 200, 150, 250), 1, false},
+		{"no land", nil, 1, false}}
+	for _, test := range tests {
+		if transects, err = castTransects(baseline, test.land, 50); err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(transects) != 2 {
+			t.Fatalf("Expected 2 transects with %v, received %v", test.name, len(transects))
+		}
+		for _, current := range transects {
+			if current.nx != 0 || current.ny != test.ny || current.seaward != test.seaward {
+				t.Errorf("Expected a direction of (0, %v), seaward %v, with %v, received (%v, %v), seaward %v",
+					test.ny, test.seaward, test.name, current.nx, current.ny, current.seaward)
+			}
+		}
+	}
+	if _, err = castTransects(baseline, nil, 0); err == nil {
+		t.Error("Expected an error for a spacing of 0")
+	}
+}
+
+// TestCastTransectsMultiLineString casts transects from each part of a baseline,
+// continuing the stations from one part to the next
+func TestCastTransectsMultiLineString(t *testing.T) {
+	var (
+		baseline  *geos.Geometry
+		land      *geos.Geometry
+		transects []transect
+		err       error
+	)
+	// The second part is digitized westward, with the land to its left
+	if baseline, err = toGeos(&geojson.MultiLineString{
+		Type:        geojson.MULTILINESTRING,
+		Coordinates: [][][]float64{{{0, 0}, {100, 0}}, {{60, 50}, {0, 50}}}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if land, err = rectangle(t, -50, -50, 150, 0).Union(rectangle(t, -50, 20, 110, 50)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if transects, err = castTransects(baseline, land, 40); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []transect{
+		{origin: geos.NewCoord(0, 0), ny: 1, station: 0, seaward: true},
+		{origin: geos.NewCoord(40, 0), ny: 1, station: 40, seaward: true},
+		{origin: geos.NewCoord(80, 0), ny: 1, station: 80, seaward: true},
+		{origin: geos.NewCoord(40, 50), ny: 1, station: 120, seaward: true}}
+	if len(transects) != len(expected) {
+		t.Fatalf("Expected %v transects, received %v", len(expected), len(transects))
+	}
+	for inx, current := range transects {
+		want := expected[inx]
+		if current.origin.X != want.origin.X || current.origin.Y != want.origin.Y ||
+			current.nx != 0 || current.ny != want.ny || current.station != want.station || current.seaward != want.seaward {
+			t.Errorf("Expected transect %v to be %+v, received %+v", inx, want, current)
+		}
+	}
+}
+
+// TestTransectStatistics fits rates to shorelines retreating 2 m/yr with some noise
+func TestTransectStatistics(t *testing.T) {
+	var scenes []transectScene
+	for year := 2000; year <= 2008; year += 2 {
+		scenes = append(scenes, transectScene{date: time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)})
+	}
+	// An undated shoreline counts toward the envelope but not the rates
+	scenes = append([]transectScene{{}}, scenes...)
+	positions := []shorelinePosition{{0, 30}, {1, 0}, {2, -5}, {3, -7}, {4, -13}, {5, -15}}
+	properties := transectStatistics(positions, scenes, time.Time{}, transectOptions{confidence: 95, uncertainty: 2})
+	if shorelines := properties[SHORELINES].(int); shorelines != 6 {
+		t.Errorf("Expected 6 shorelines, received %v", shorelines)
+	}
+	if sce := properties[SCE].(float64); sce != 45 {
+		t.Errorf("Expected a shoreline change envelope of 45, received %v", sce)
+	}
+	// Net movement runs from the oldest dated shoreline, not the undated one
+	if nsm := properties[NSM].(float64); nsm != -15 {
+		t.Errorf("Expected a net shoreline movement of -15, received %v", nsm)
+	}
+	years := float64(scenes[5].date.Sub(scenes[1].date)) / float64(yearLength)
+	if epr := properties[EPR].(float64); math.Abs(epr+15/years) > 1e-9 {
+		t.Errorf("Expected an end point rate of %v, received %v", -15/years, epr)
+	}
+	if eci := properties[ECI].(float64); math.Abs(eci-math.Sqrt2*2/years) > 1e-9 {
+		t.Errorf("Expected an end point rate interval of %v, received %v", math.Sqrt2*2/years, eci)
+	}
+	if lrr := properties[LRR].(float64); math.Abs(lrr+1.9) > 0.01 {
+		t.Errorf("Expected a linear regression rate near -1.9, received %v", lrr)
+	}
+	if lci := properties[LCI].(float64); lci <= 0 || lci > 1 {
+		t.Errorf("Expected a linear regression rate interval between 0 and 1, received %v", lci)
+	}
+	if lr2 := properties[LR2].(float64); lr2 < 0.95 || lr2 > 1 {
+		t.Errorf("Expected a coefficient of determination near 1, received %v", lr2)
+	}
+
+	// Without two dated shorelines net movement runs from the first to the last
+	properties = transectStatistics([]shorelinePosition{{0, 30}, {1, 0}}, []transectScene{{}, {}}, time.Time{}, transectOptions{confidence: 95})
+	if nsm := properties[NSM].(float64); nsm != -30 {
+		t.Errorf("Expected a net shoreline movement of -30 between undated shorelines, received %v", nsm)
+	}
+	if _, ok := properties[EPR]; ok {
+		t.Error("Expected no end point rate between undated shorelines")
+	}
+
+	// Two dated shorelines are too few for an envelope or a regression
+	properties = transectStatistics(positions[1:3], scenes, time.Time{}, transectOptions{confidence: 95})
+	if nsm := properties[NSM].(float64); nsm != -5 {
+		t.Errorf("Expected a net shoreline movement of -5 between two shorelines, received %v", nsm)
+	}
+	for _, key := range []string{SCE, LRR, LCI, LR2} {
+		if _, ok := properties[key]; ok {
+			t.Errorf("Expected no %v for two shorelines", key)
+		}
+	}
+
+	// One dated shoreline moves from the dated baseline, whichever is older
+	tests := []struct {
+		baselineDate time.Time
+		expected     float64
+	}{
+		{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), -7},
+		{time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), 7}}
+	for _, test := range tests {
+		properties = transectStatistics(positions[3:4], scenes, test.baselineDate, transectOptions{confidence: 95})
+		if nsm := properties[NSM].(float64); nsm != test.expected {
+			t.Errorf("Expected a net shoreline movement of %v from a baseline dated %v, received %v", test.expected, test.baselineDate, nsm)
+		}
+		years := math.Abs(float64(scenes[3].date.Sub(test.baselineDate))) / float64(yearLength)
+		if epr := properties[EPR].(float64); math.Abs(epr-test.expected/years) > 1e-9 {
+			t.Errorf("Expected an end point rate of %v from a baseline dated %v, received %v", test.expected/years, test.baselineDate, epr)
+		}
+	}
+
+	// One shoreline has no movement to measure without a dated baseline
+	for _, single := range [][]shorelinePosition{positions[:1], positions[3:4]} {
+		properties = transectStatistics(single, scenes, time.Time{}, transectOptions{confidence: 95})
+		for _, key := range []string{SCE, NSM, EPR, LRR, LCI, LR2} {
+			if _, ok := properties[key]; ok {
+				t.Errorf("Expected no %v for a single shoreline", key)
+			}
+		}
+	}
+}
+
+// TestSceneDate dates scenes by their properties and file names
+func TestSceneDate(t *testing.T) {
+	dated := func(value interface{}) *Scene {
+		feature := geojson.NewFeature(&geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {1, 1}}},
+			"", map[string]interface{}{"date": value})
+		return &Scene{geoJSON: geojson.NewFeatureCollection([]*geojson.Feature{feature})}
+	}
+	tests := []struct {
+		filename string
+		scene    *Scene
+		property string
+		expected string
+	}{
+		{"detected.geojson", dated("2016-06-01T10:30:00Z"), "date", "2016-06-01"},
+		{"detected.geojson", dated("20160601"), "date", "2016-06-01"},
+		{"detected_20160601.geojson", dated(2016), "date", "2016-06-01"},
+		{"detected_20160601.geojson", dated("2010-01-01"), "", "2016-06-01"},
+		{"data/2016-06-01/detected.geojson", dated(nil), "date", ""},
+		{"detected_2016-06-01_20170131.geojson", dated(nil), "date", "2016-06-01"},
+		{"detected_20161301_20170131.geojson", dated(nil), "date", "2017-01-31"},
+		{"detected_120160601.geojson", dated(nil), "date", ""},
+		{"detected_201606011.geojson", dated(nil), "date", ""},
+		{"detected.geojson", dated(nil), "date", ""}}
+	for _, test := range tests {
+		result, err := sceneDate(test.filename, test.scene, test.property)
+		if err != nil {
+			t.Errorf("Could not date %v: %v", test.filename, err)
+		} else if result != test.expected {
+			t.Errorf("Expected %v to be dated %v, received %v", test.filename, test.expected, result)
+		}
+	}
+	if _, err := sceneDate("detected.geojson", dated("June 2016"), "date"); err == nil {
+		t.Error("Expected an error for an unreadable date")
+	}
+	geometry := &Scene{geoJSON: &geojson.LineString{Type: geojson.LINESTRING, Coordinates: [][]float64{{0, 0}, {1, 1}}}}
+	if _, err := sceneDate("detected_20160601.geojson", geometry, "date"); err == nil {
+		t.Error("Expected an error for a scene without features")
+	}
+}